@@ -14,6 +14,8 @@
 
 package config
 
+import "time"
+
 const (
 	HTTPBasicAuthUsername = "httpBasicAuthUsername"
 	HTTPBasicAuthPassword = "httpBasicAuthPassword"
@@ -21,6 +23,38 @@ const (
 	DataType              = "dataType"
 	BaseURL               = "baseURL"
 	ClassificationMarking = "classificationMarking"
+	RetryMaxAttempts      = "retryMaxAttempts"
+	RetryTimeout          = "retryTimeout"
+	RateLimit             = "rateLimit"
+	RateLimitBurst        = "rateLimitBurst"
+	MaxRecordsPerRequest  = "maxRecordsPerRequest"
+	MaxBytesPerRequest    = "maxBytesPerRequest"
+	TransformErrorPolicy  = "transformErrorPolicy"
+	EphemerisType         = "ephemerisType"
+	EphemerisCategory     = "ephemerisCategory"
+	EphemerisFormatType   = "ephemerisFormatType"
+	EphemerisSource       = "ephemerisSource"
+	EphemerisOrigin       = "ephemerisOrigin"
+	EphemerisHasManeuver  = "ephemerisHasManeuver"
+	VelocityMethod        = "velocityMethod"
+	VelocityPoints        = "velocityPoints"
+	EphemerisInputFormat  = "ephemerisInputFormat"
+	ElsetSource           = "elsetSource"
+	TLEMakeCurrent        = "tleMakeCurrent"
+	TLESubmitMethod       = "tleSubmitMethod"
+	ElsetFillMissing      = "elsetFillMissing"
+	AISInputFormat        = "aisInputFormat"
+	NMEASource            = "nmeaSource"
+	SpireSchemaVersion    = "spireSchemaVersion"
+	ShipTypeMappingFile   = "shipTypeMappingFile"
+	UnmappedShipType      = "unmappedShipType"
+	NoradSources          = "noradSources"
+	NoradMappingFile      = "noradMappingFile"
+	SP3SatelliteFile      = "sp3SatelliteFile"
+	NoradCacheTTL         = "noradCacheTTL"
+	SatelliteNameSources  = "satelliteNameSources"
+	SatelliteNameFile     = "satelliteNameFile"
+	SatelliteNameMismatch = "satelliteNameMismatch"
 )
 
 type Config struct {
@@ -30,10 +64,74 @@ type Config struct {
 	HTTPBasicAuthPassword string `validate:"required"`
 	// The Data Mode to use when submitting requests to the UDL. Acceptable values are REAL, TEST, SIMULATED and EXERCISE.
 	DataMode string `validate:"inclusion=REAL|TEST|SIMULATED|EXERCISE" default:"TEST"`
-	// The Data Type that is being submitted to the UDL. Acceptable values are AIS and ELSET.
-	DataType string `validate:"inclusion=AIS|ELSET|EPHEMERIS" default:"AIS"`
+	// The Data Type that is being submitted to the UDL. Acceptable values are AIS, ELSET, EPHEMERIS and TLE.
+	DataType string `validate:"inclusion=AIS|ELSET|EPHEMERIS|TLE" default:"AIS"`
 	// The Base URL to use to access the UDL. The default is https://unifieddatalibrary.com.
 	BaseURL string `default:"https://unifieddatalibrary.com"`
 	// Classification marking of the data in IC/CAPCO Portion-marked format. The default is U
 	ClassificationMarking string `default:"U"`
+	// The maximum number of times a request is sent to the UDL when it fails with 429, a 5xx status or a network error. 4xx errors are never retried.
+	RetryMaxAttempts int `default:"5"`
+	// The maximum total time spent on a request to the UDL, including all attempts and the waits between them.
+	RetryTimeout time.Duration `default:"2m"`
+	// The maximum number of requests per second sent to the UDL, shared by all endpoints. 0 disables the limit.
+	RateLimit float64 `default:"0"`
+	// The maximum number of requests sent to the UDL in a single burst when rateLimit is set.
+	RateLimitBurst int `default:"1"`
+	// The maximum number of AIS records, elsets or TLEs sent to the UDL in a single request. Larger batches are split into several requests. 0 disables the limit.
+	MaxRecordsPerRequest int `default:"0"`
+	// The maximum size in bytes of the body of a single AIS or elset filedrop or TLE request. Larger batches are split into several requests. 0 disables the limit.
+	MaxBytesPerRequest int `default:"0"`
+	// What to do with a record that can not be transformed into the UDL model. fail stops the pipeline, skip logs and drops the record, dlq nacks the record so the pipeline's dead-letter queue receives it.
+	TransformErrorPolicy string `validate:"inclusion=fail|skip|dlq" default:"fail"`
+	// The type/purpose of submitted ephemeris, e.g. LAUNCH, ROUTINE, MNVR_PLAN or SCREENING.
+	EphemerisType string `default:"ROUTINE"`
+	// The source category of submitted ephemeris, e.g. OWNER_OPERATOR, ANALYST or EXTERNAL.
+	EphemerisCategory string `default:"EXTERNAL"`
+	// The format of submitted ephemeris as documented in the Flight Safety Handbook. Acceptable values are GOO, ModITC, NASA, OASYS and OEM. With OEM the ephemeris are submitted as CCSDS OEM, otherwise in the text format.
+	EphemerisFormatType string `validate:"inclusion=GOO|ModITC|NASA|OASYS|OEM" default:"NASA"`
+	// The source of submitted ephemeris.
+	EphemerisSource string `default:"Spire"`
+	// The originating system or organization of submitted ephemeris, if different from the source.
+	EphemerisOrigin string
+	// Whether maneuvers are incorporated into submitted ephemeris.
+	EphemerisHasManeuver bool `default:"false"`
+	// How velocities are derived for SP3 files without velocity records. lagrange differentiates a Lagrange interpolating polynomial through velocityPoints positions, difference uses central differences, none rejects such files.
+	VelocityMethod string `validate:"inclusion=lagrange|difference|none" default:"lagrange"`
+	// The number of positions the Lagrange interpolating polynomial is fitted through when velocityMethod is lagrange.
+	VelocityPoints int `validate:"gt=1" default:"9"`
+	// The format of ephemeris records. Acceptable values are sp3, oem (CCSDS OEM in KVN or XML) and auto, which detects the format of every record.
+	EphemerisInputFormat string `validate:"inclusion=auto|sp3|oem" default:"auto"`
+	// The source of submitted elsets.
+	ElsetSource string `default:"Spire"`
+	// Whether elsets created from TLEs are set as the current elset of their satellite.
+	TLEMakeCurrent bool `default:"false"`
+	// How TLEs are submitted. bulk posts the TLE text to createBulkFromTLE, filedrop parses the TLEs into elsets and posts them to the elset filedrop.
+	TLESubmitMethod string `validate:"inclusion=bulk|filedrop" default:"bulk"`
+	// Whether dataMode, classificationMarking and elsetSource only fill in the values missing from ELSET records. By default they replace the values of the records.
+	ElsetFillMissing bool `default:"false"`
+	// The format of AIS records. Acceptable values are spire (Spire vessel JSON), nmea (NMEA 0183 AIVDM/AIVDO sentences, one per line) and auto, which detects the format of every record.
+	AISInputFormat string `validate:"inclusion=auto|spire|nmea" default:"auto"`
+	// The source of AIS records decoded from NMEA sentences, usually the provider of the AIS feed. Spire vessel JSON is always submitted with source Spire.
+	NMEASource string `default:"Spire"`
+	// The schema version of Spire vessel JSON. 1 is the Spire Vessels REST API, 2 is Spire Maritime 2.0 (GraphQL) and auto detects the version of every record.
+	SpireSchemaVersion string `validate:"inclusion=auto|1|2" default:"auto"`
+	// Path of a JSON file with ship type mappings that are layered on top of the built-in mappings of Spire ship types.
+	ShipTypeMappingFile string
+	// What to do with a ship type that has no mapping. Acceptable values are fail, passthrough (the ship type is submitted as is) and other (the ship type is submitted as Other).
+	UnmappedShipType string `validate:"inclusion=fail|passthrough|other" default:"other"`
+	// Comma-separated list of the sources the NORAD IDs of Spire flight modules are resolved through, in order. Acceptable sources are builtin (the table compiled into the connector), file (noradMappingFile) and udl (the current elsets of elsetSource in the UDL).
+	NoradSources string `default:"builtin"`
+	// Path of a JSON file mapping flight module numbers to NORAD IDs, e.g. {"144": 46502}. The file is read again when it changes.
+	NoradMappingFile string
+	// Path of a JSON file mapping SP3 satellite IDs to NORAD IDs, e.g. {"G01": 37753}. Satellites that are not Spire flight modules, such as those of GNSS precise orbit products, are resolved through it. The file is read again when it changes.
+	SP3SatelliteFile string
+	// How long NORAD IDs looked up in the UDL are cached.
+	NoradCacheTTL time.Duration `default:"1h"`
+	// Comma-separated list of the sources the NORAD IDs of SP3 satellite names and OEM objects are resolved through, in order. Acceptable sources are file (satelliteNameFile) and udl (the on-orbit objects of the current elsets in the UDL). By default satellite names are not resolved.
+	SatelliteNameSources string
+	// Path of a JSON file mapping satellite names to NORAD IDs, e.g. {"LEMUR-2-JOHN-TREIRES": 46502}. The file is read again when it changes.
+	SatelliteNameFile string
+	// What to do with a satellite whose name and flight module resolve to different NORAD IDs. Acceptable values are reject and flag, which submits the ephemeris under the NORAD ID of the flight module and logs the mismatch.
+	SatelliteNameMismatch string `validate:"inclusion=reject|flag" default:"reject"`
 }
@@ -17,9 +17,11 @@ package udl
 import (
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/meroxa/conduit-connector-udl-public/destination"
+	"github.com/meroxa/conduit-connector-udl-public/source"
 )
 
 var Connector = sdk.Connector{
 	NewSpecification: Specification,
+	NewSource:        source.NewSource,
 	NewDestination:   destination.NewDestination,
 }
@@ -0,0 +1,89 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"encoding/json"
+)
+
+// bodyFormat describes how a chunk is encoded in a request body, so chunks can
+// be measured in the bytes actually sent.
+type bodyFormat[T any] struct {
+	// size returns the encoded size of an item.
+	size func(T) (int, error)
+	// frame is the size of the encoding of an empty chunk, sep the size of
+	// the separator between two items.
+	frame, sep int
+}
+
+// jsonArray encodes a chunk as a JSON array of its items.
+func jsonArray[T any]() bodyFormat[T] {
+	return bodyFormat[T]{
+		size: func(item T) (int, error) {
+			b, err := json.Marshal(item)
+			return len(b), err
+		},
+		frame: 2,
+		sep:   1,
+	}
+}
+
+// plainText encodes a chunk as its items joined without separator.
+var plainText = bodyFormat[string]{size: func(s string) (int, error) { return len(s), nil }}
+
+// chunk splits batch into consecutive chunks of at most maxRecords items whose
+// encoding in format is at most maxBytes long. A limit of 0 or less is not
+// enforced. An item that exceeds maxBytes on its own is put in a chunk by
+// itself and left for the UDL to reject. An empty batch has no chunks.
+func chunk[T any](batch []T, maxRecords, maxBytes int, format bodyFormat[T]) ([][]T, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	if maxRecords <= 0 && maxBytes <= 0 {
+		return [][]T{batch}, nil
+	}
+
+	var (
+		chunks [][]T
+		start  int
+		// size is the encoded size of batch[start:i] including the frame
+		// and the separators
+		size = format.frame
+	)
+	for i, item := range batch {
+		itemSize := 0
+		if maxBytes > 0 {
+			var err error
+			if itemSize, err = format.size(item); err != nil {
+				return nil, err
+			}
+		}
+
+		n := i - start
+		sep := 0
+		if n > 0 {
+			sep = format.sep
+		}
+		full := maxRecords > 0 && n >= maxRecords
+		tooBig := maxBytes > 0 && n > 0 && size+sep+itemSize > maxBytes
+		if full || tooBig {
+			chunks = append(chunks, batch[start:i])
+			start, size, sep = i, format.frame, 0
+		}
+		size += sep + itemSize
+	}
+	chunks = append(chunks, batch[start:])
+	return chunks, nil
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name       string
+		batch      []string
+		maxRecords int
+		maxBytes   int
+		want       [][]string
+	}{
+		{
+			name: "empty batch",
+		},
+		{
+			name:       "empty batch with limits",
+			maxRecords: 2,
+			maxBytes:   12,
+		},
+		{
+			name:  "no limits",
+			batch: []string{"a", "b", "c"},
+			want:  [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:       "record limit",
+			batch:      []string{"a", "b", "c", "d", "e"},
+			maxRecords: 2,
+			want:       [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+		{
+			// ["a","b"] is 9 bytes, ["a","b","c"] is 13 bytes
+			name:     "byte limit",
+			batch:    []string{"a", "b", "c", "d"},
+			maxBytes: 12,
+			want:     [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			name:     "oversized item gets its own chunk",
+			batch:    []string{"a", "this item is too long", "b"},
+			maxBytes: 10,
+			want:     [][]string{{"a"}, {"this item is too long"}, {"b"}},
+		},
+		{
+			name:       "both limits",
+			batch:      []string{"a", "b", "c", "d", "e"},
+			maxRecords: 3,
+			maxBytes:   12,
+			want:       [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			got, err := chunk(tt.batch, tt.maxRecords, tt.maxBytes, jsonArray[string]())
+			is.NoErr(err)
+			is.Equal(got, tt.want)
+		})
+	}
+}
+
+func TestChunk_PlainText(t *testing.T) {
+	is := is.New(t)
+	// measured as JSON, "a\"b\n" is 8 bytes and the three items would not fit
+	batch := []string{"a\"b\n", "cd\n", "e\n"}
+
+	got, err := chunk(batch, 0, 9, plainText)
+	is.NoErr(err)
+	is.Equal(got, [][]string{batch}) // 4+3+2 bytes of text are sent
+	got, err = chunk(batch, 0, 8, plainText)
+	is.NoErr(err)
+	is.Equal(got, [][]string{{"a\"b\n", "cd\n"}, {"e\n"}})
+}
+
+func TestWriteAis_ChunkFailure(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{
+		mockAis: {at: 2, status: http.StatusRequestEntityTooLarge, body: "request entity too large"},
+	}}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	dest.Config.MaxRecordsPerRequest = 2
+
+	records := make([]sdk.Record, 5)
+	for i := range records {
+		records[i] = aisRecord()
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.True(err != nil)
+	is.Equal(n, 4) // the records of the first two chunks were acknowledged
+	is.Equal(client.aisSizes, []int{2, 2})
+	is.True(strings.Contains(err.Error(), "request entity too large"))
+}
+
+func TestWriteAis_NoMessages(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	// base station reports decode to no AIS records
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType4)}},
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType4)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	is.Equal(len(client.aisSizes), 0) // nothing is posted
+}
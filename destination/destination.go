@@ -17,16 +17,27 @@ package destination
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/deepmap/oapi-codegen/pkg/securityprovider"
 	"github.com/meroxa/conduit-connector-udl-public/udl"
 )
 
+const (
+	retryMinBackoff = 500 * time.Millisecond
+	retryMaxBackoff = 30 * time.Second
+)
+
 type Destination struct {
 	sdk.UnimplementedDestination
 	Config Config
 	client udl.ClientInterface
+	// shipTypes holds the ship type mappings of AIS records
+	shipTypes *ShipTypeMapping
+	// norad resolves the NORAD IDs of satellites in SP3 files and OEMs
+	norad *NoradResolver
 }
 
 func NewDestination() sdk.Destination {
@@ -44,7 +55,28 @@ func (d *Destination) Configure(ctx context.Context, cfg map[string]string) erro
 		sdk.Logger(context.Background()).Err(err).Msgf("invalid config")
 		return err
 	}
-	return nil
+	w, err := writerFor(d.Config.DataType)
+	if err != nil {
+		return err
+	}
+	d.Config.DataType = canonicalDataType(d.Config.DataType)
+	d.Config.TransformErrorPolicy = transformErrorPolicy(d.Config.TransformErrorPolicy)
+	if !validTransformErrorPolicy(d.Config.TransformErrorPolicy) {
+		return fmt.Errorf("unsupported transform error policy: %s", d.Config.TransformErrorPolicy)
+	}
+	if batchedWrites(cfg) {
+		sdk.Logger(ctx).Warn().Msg("sdk.batch.size or sdk.batch.delay is set: records of a batch that fails are not acknowledged one by one, the written ones are nacked and the rest are never acked")
+	}
+	return w.validate(d.Config)
+}
+
+// batchedWrites reports whether the SDK middleware batches records before
+// writing them. The batching of SDK v0.7.2 does not ack a partially written
+// batch record by record, see firstError.
+func batchedWrites(cfg map[string]string) bool {
+	size, _ := strconv.Atoi(cfg["sdk.batch.size"])
+	delay, _ := time.ParseDuration(cfg["sdk.batch.delay"])
+	return size > 0 || delay > 0
 }
 
 func (d *Destination) Open(ctx context.Context) error {
@@ -52,28 +84,64 @@ func (d *Destination) Open(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	c, err := udl.NewClient(d.Config.BaseURL, udl.WithRequestEditorFn(authProvider.Intercept))
+	c, err := udl.NewClient(d.Config.BaseURL,
+		udl.WithRequestEditorFn(authProvider.Intercept),
+		udl.WithRateLimit(d.Config.RateLimit, d.Config.RateLimitBurst),
+		udl.WithRetry(udl.RetryConfig{
+			MaxAttempts: d.Config.RetryMaxAttempts,
+			MaxDuration: d.Config.RetryTimeout,
+			MinBackoff:  retryMinBackoff,
+			MaxBackoff:  retryMaxBackoff,
+		}))
 	if err != nil {
 		return err
 	}
 	d.client = c
-	return nil
+	// records are routed to EPHEMERIS by metadata as well, so the resolver
+	// is built whatever the configured data type is
+	d.norad, err = newNoradResolver(d.Config, c)
+	return err
 }
 
 func (d *Destination) Write(ctx context.Context, records []sdk.Record) (int, error) {
-	dataType := d.Config.DataType
+	groups, err := d.groupByDataType(records)
+	if err != nil {
+		return 0, err
+	}
+
+	errs := make([]error, len(records))
+	for _, g := range groups {
+		sdk.Logger(ctx).Debug().Msgf("writing %d records of dataType %s", len(g.records), g.dataType)
+		failed := false
+		for k, err := range d.writeGroup(ctx, g) {
+			errs[g.indices[k]] = err
+			failed = failed || err != nil
+		}
+		if failed {
+			// records after a failed one are redelivered, submitting them
+			// now would post them twice
+			notWritten(errs[g.indices[len(g.indices)-1]+1:])
+			break
+		}
+	}
+	return firstError(errs)
+}
 
-	sdk.Logger(context.Background()).Debug().Msgf("dataType selected: %s", d.Config.DataType)
-	switch dataType {
-	case "AIS":
-		return d.writeAisToUDL(ctx, records)
-	case "Elset":
-		return d.writeElsetToUDL(ctx, records)
-	case "EPHEMERIS":
-		return d.writeEphemerisToUDL(ctx, records)
-	default:
-		return 0, fmt.Errorf("unsupported data type: %s;", dataType)
+// writeGroup writes the records of a group and returns the error of every
+// record, nil for a record that was written.
+func (d *Destination) writeGroup(ctx context.Context, g *recordGroup) []error {
+	w := writers[g.dataType]
+	if g.dataType != canonicalDataType(d.Config.DataType) {
+		// the configured data type was validated in Configure
+		if err := w.validate(d.Config); err != nil {
+			errs := make([]error, len(g.records))
+			for i := range errs {
+				errs[i] = err
+			}
+			return errs
+		}
 	}
+	return w.write(ctx, d, g.records)
 }
 
 func (d *Destination) Teardown(ctx context.Context) error {
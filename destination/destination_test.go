@@ -16,31 +16,17 @@ package destination
 
 import (
 	"context"
-	"net/http"
 	"testing"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/matryer/is"
-
-	"github.com/meroxa/conduit-connector-udl-public/udl"
 )
 
-type mockClient struct {
-	udl.ClientInterface
-}
-
-func (c *mockClient) FiledropUdlAisPostId(ctx context.Context, body udl.FiledropUdlAisPostIdJSONRequestBody, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
-	// Since this is a mock function, you can simply return a successful HTTP response without making an actual API call
-	return &http.Response{
-		StatusCode: http.StatusOK,
-	}, nil
-}
-
 func TestParameters(t *testing.T) {
 	is := is.New(t)
 	d := Destination{}
 	params := d.Parameters()
-	is.Equal(len(params), 6) // Assumes there are 6 parameters in the config
+	is.Equal(len(params), 38) // one parameter per field of config.Config
 }
 
 func TestConfigure(t *testing.T) {
@@ -73,6 +59,7 @@ func TestOpen(t *testing.T) {
 	err = dest.Open(ctx)
 	is.NoErr(err)
 	is.True(dest.client != nil)
+	is.True(dest.norad != nil) // records can be routed to EPHEMERIS by metadata
 }
 
 func TestWrite(t *testing.T) {
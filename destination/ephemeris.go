@@ -1,15 +1,37 @@
 package destination
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const udlTimeLayout = "06002150405.000"
 
 type UDLReport struct {
-	ID      string
-	Entries []UDLEntry
+	ID string
+	// SatelliteID is the ID of the satellite in the source file
+	SatelliteID string
+	// ObjectName is the name of the satellite, if known
+	ObjectName string
+	// CenterName is the origin of the reference frame, e.g. EARTH
+	CenterName string
+	// RefFrame is the reference frame of the states, e.g. ITRF or EME2000
+	RefFrame string
+	// TimeSystem is the time system of the epochs, e.g. UTC or GPS
+	TimeSystem string
+	// Comments are notes on the report, written as COMMENT lines of an OEM
+	Comments []string
+	Entries  []UDLEntry
+	// Covariances are the covariance matrices of an OEM input. Only the OEM
+	// output has room for them, the text format drops them.
+	Covariances []OEMCovariance
 }
 
 type UDLEntry struct {
+	Epoch     time.Time
 	Timestamp string
 	Position  UDLPosition
 	Velocity  UDLVelocity
@@ -47,3 +69,76 @@ func (r UDLReport) String() string {
 	}
 	return out
 }
+
+// oemTimeLayout is the CCSDS ASCII calendar time code used in OEM output.
+const oemTimeLayout = "2006-01-02T15:04:05.000000"
+
+// OEM returns the report as a CCSDS OEM in KVN format with the NORAD ID as
+// OBJECT_ID, followed by the covariance matrices of the report.
+func (r UDLReport) OEM(created time.Time, originator string) (string, error) {
+	if len(r.Entries) == 0 {
+		return "", errors.New("report contains no entries")
+	}
+	if r.RefFrame == "" {
+		return "", fmt.Errorf("report of %s has no reference frame", r.ID)
+	}
+	name := r.ObjectName
+	if name == "" {
+		name = r.ID
+	}
+	center := r.CenterName
+	if center == "" {
+		center = "EARTH"
+	}
+	timeSystem := r.TimeSystem
+	if timeSystem == "" {
+		timeSystem = "UTC"
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "CCSDS_OEM_VERS = 2.0\n")
+	fmt.Fprintf(&out, "CREATION_DATE = %s\n", created.UTC().Format(oemTimeLayout))
+	fmt.Fprintf(&out, "ORIGINATOR = %s\n\n", originator)
+	fmt.Fprintf(&out, "META_START\n")
+	fmt.Fprintf(&out, "OBJECT_NAME = %s\n", name)
+	fmt.Fprintf(&out, "OBJECT_ID = %s\n", r.ID)
+	fmt.Fprintf(&out, "CENTER_NAME = %s\n", center)
+	fmt.Fprintf(&out, "REF_FRAME = %s\n", r.RefFrame)
+	fmt.Fprintf(&out, "TIME_SYSTEM = %s\n", timeSystem)
+	fmt.Fprintf(&out, "START_TIME = %s\n", r.Entries[0].Epoch.Format(oemTimeLayout))
+	fmt.Fprintf(&out, "STOP_TIME = %s\n", r.Entries[len(r.Entries)-1].Epoch.Format(oemTimeLayout))
+	fmt.Fprintf(&out, "META_STOP\n\n")
+	for _, c := range r.Comments {
+		fmt.Fprintf(&out, "COMMENT %s\n", c)
+	}
+	for _, e := range r.Entries {
+		fmt.Fprintf(&out, "%s %s %s %s %s %s %s\n",
+			e.Epoch.Format(oemTimeLayout),
+			e.Position.X,
+			e.Position.Y,
+			e.Position.Z,
+			e.Velocity.X,
+			e.Velocity.Y,
+			e.Velocity.Z)
+	}
+	if len(r.Covariances) > 0 {
+		fmt.Fprintf(&out, "\nCOVARIANCE_START\n")
+		for _, c := range r.Covariances {
+			fmt.Fprintf(&out, "EPOCH = %s\n", c.Epoch.Format(oemTimeLayout))
+			if c.RefFrame != "" {
+				fmt.Fprintf(&out, "COV_REF_FRAME = %s\n", c.RefFrame)
+			}
+			// the lower triangle has one element in its first row, two in
+			// its second and so on
+			for row, n := 1, 0; n < len(c.Matrix); row, n = row+1, n+row {
+				elements := make([]string, row)
+				for k := range elements {
+					elements[k] = strconv.FormatFloat(c.Matrix[n+k], 'e', 7, 64)
+				}
+				fmt.Fprintf(&out, "%s\n", strings.Join(elements, " "))
+			}
+		}
+		fmt.Fprintf(&out, "COVARIANCE_STOP\n")
+	}
+	return out.String(), nil
+}
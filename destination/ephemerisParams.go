@@ -0,0 +1,170 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// Record metadata keys overriding the configured ephemeris filedrop
+// parameters for a single record.
+const (
+	MetadataEphemerisDataMode       = "udl.ephemeris.dataMode"
+	MetadataEphemerisClassification = "udl.ephemeris.classification"
+	MetadataEphemerisType           = "udl.ephemeris.type"
+	MetadataEphemerisCategory       = "udl.ephemeris.category"
+	MetadataEphemerisFormatType     = "udl.ephemeris.formatType"
+	MetadataEphemerisSource         = "udl.ephemeris.source"
+	MetadataEphemerisOrigin         = "udl.ephemeris.origin"
+	MetadataEphemerisHasManeuver    = "udl.ephemeris.hasMnvr"
+)
+
+// Defaults of the ephemeris filedrop parameters, used when the config is
+// parsed without the parameter defaults applied.
+const (
+	defaultEphemerisType       = "ROUTINE"
+	defaultEphemerisCategory   = "EXTERNAL"
+	defaultEphemerisFormatType = "NASA"
+	defaultEphemerisSource     = "Spire"
+)
+
+var (
+	ephemerisDataModes = []udl.DataMode{
+		udl.DataModeEXERCISE,
+		udl.DataModeREAL,
+		udl.DataModeSIMULATED,
+		udl.DataModeTEST,
+	}
+	ephemerisFormatTypes = []udl.EphemFormatType{
+		udl.GOO,
+		udl.ModITC,
+		udl.NASA,
+		udl.OASYS,
+		udl.OEM,
+	}
+)
+
+// ephemerisUpload is an ephemeris report together with the filedrop
+// parameters and body it is submitted with.
+type ephemerisUpload struct {
+	report UDLReport
+	params udl.FiledropEphemPostIdParams
+	body   string
+}
+
+// newEphemerisUpload renders the report in the format of the ephemFormatType
+// parameter: a CCSDS OEM for OEM, the text format otherwise.
+func newEphemerisUpload(report UDLReport, params udl.FiledropEphemPostIdParams, created time.Time) (ephemerisUpload, error) {
+	upload := ephemerisUpload{report: report, params: params, body: report.String()}
+	if params.EphemFormatType == udl.OEM {
+		originator := params.Source
+		if params.Origin != nil {
+			originator = *params.Origin
+		}
+		var err error
+		if upload.body, err = report.OEM(created, originator); err != nil {
+			return ephemerisUpload{}, err
+		}
+	}
+	return upload, nil
+}
+
+// ephemerisParams returns the filedrop parameters for a report of the
+// satellite idOnOrbit, taking the config values unless the record metadata
+// overrides them.
+func ephemerisParams(idOnOrbit string, cfg Config, md sdk.Metadata) (udl.FiledropEphemPostIdParams, error) {
+	get := func(key, value, def string) string {
+		if v, ok := md[key]; ok && v != "" {
+			return v
+		}
+		if value == "" {
+			return def
+		}
+		return value
+	}
+
+	dataMode, err := parseEphemerisDataMode(get(MetadataEphemerisDataMode, cfg.DataMode, string(udl.DataModeTEST)))
+	if err != nil {
+		return udl.FiledropEphemPostIdParams{}, err
+	}
+	formatType, err := parseEphemFormatType(get(MetadataEphemerisFormatType, cfg.EphemerisFormatType, defaultEphemerisFormatType))
+	if err != nil {
+		return udl.FiledropEphemPostIdParams{}, err
+	}
+	hasMnvr := cfg.EphemerisHasManeuver
+	if v, ok := md[MetadataEphemerisHasManeuver]; ok && v != "" {
+		hasMnvr, err = strconv.ParseBool(v)
+		if err != nil {
+			return udl.FiledropEphemPostIdParams{}, fmt.Errorf("invalid %s: %w", MetadataEphemerisHasManeuver, err)
+		}
+	}
+
+	params := udl.FiledropEphemPostIdParams{
+		IdOnOrbit:       idOnOrbit,
+		Classification:  get(MetadataEphemerisClassification, cfg.ClassificationMarking, ""),
+		DataMode:        dataMode,
+		HasMnvr:         hasMnvr,
+		Type:            get(MetadataEphemerisType, cfg.EphemerisType, defaultEphemerisType),
+		Category:        get(MetadataEphemerisCategory, cfg.EphemerisCategory, defaultEphemerisCategory),
+		EphemFormatType: formatType,
+		Source:          get(MetadataEphemerisSource, cfg.EphemerisSource, defaultEphemerisSource),
+	}
+	if origin := get(MetadataEphemerisOrigin, cfg.EphemerisOrigin, ""); origin != "" {
+		params.Origin = &origin
+	}
+	return params, nil
+}
+
+// checkEphemeris validates the configured ephemeris input format, velocity
+// derivation, NORAD ID sources and filedrop parameters.
+func checkEphemeris(cfg Config) error {
+	switch strings.ToLower(strings.TrimSpace(cfg.EphemerisInputFormat)) {
+	case "", ephemerisFormatAuto, ephemerisFormatSP3, ephemerisFormatOEM:
+	default:
+		return fmt.Errorf("unsupported ephemeris input format: %s", cfg.EphemerisInputFormat)
+	}
+	if err := velocityOptions(cfg).validate(); err != nil {
+		return err
+	}
+	if _, err := newNoradResolver(cfg, nil); err != nil {
+		return err
+	}
+	_, err := ephemerisParams("", cfg, nil)
+	return err
+}
+
+func parseEphemerisDataMode(v string) (udl.DataMode, error) {
+	for _, m := range ephemerisDataModes {
+		if strings.EqualFold(v, string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported ephemeris data mode: %s", v)
+}
+
+func parseEphemFormatType(v string) (udl.EphemFormatType, error) {
+	for _, f := range ephemerisFormatTypes {
+		if strings.EqualFold(v, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported ephemeris format type: %s", v)
+}
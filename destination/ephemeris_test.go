@@ -28,8 +28,58 @@ func TestUDLReport_String(t *testing.T) {
 	}
 }
 
+func TestUDLReport_OEM(t *testing.T) {
+	report := UDLReport{
+		ID:         "48925",
+		ObjectName: "LEMUR-2-JOHN-TREIRES",
+		RefFrame:   "ITRF",
+		TimeSystem: "GPS",
+		Entries:    []UDLEntry{SampleUDLEntry()},
+	}
+	want := `CCSDS_OEM_VERS = 2.0
+CREATION_DATE = 2023-01-02T03:04:05.000000
+ORIGINATOR = Spire
+
+META_START
+OBJECT_NAME = LEMUR-2-JOHN-TREIRES
+OBJECT_ID = 48925
+CENTER_NAME = EARTH
+REF_FRAME = ITRF
+TIME_SYSTEM = GPS
+START_TIME = 2009-01-02T03:04:05.000000
+STOP_TIME = 2009-01-02T03:04:05.000000
+META_STOP
+
+2009-01-02T03:04:05.000000 854.324972 -806.523053 7049.922417 6.895812284 -2.628367346 -1.133733106
+`
+	got, err := report.OEM(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), "Spire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("OEM() = %v, want %v", got, want)
+	}
+
+	// the output is a valid OEM
+	oem, err := ParseOEM([]byte(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oem.Segments[0].Metadata.ObjectID != "48925" {
+		t.Errorf("OBJECT_ID = %v, want 48925", oem.Segments[0].Metadata.ObjectID)
+	}
+}
+
+func TestUDLReport_OEMWithoutRefFrame(t *testing.T) {
+	report := UDLReport{ID: "48925", Entries: []UDLEntry{SampleUDLEntry()}}
+	if _, err := report.OEM(time.Now(), "Spire"); err == nil {
+		t.Error("OEM() without reference frame should fail")
+	}
+}
+
 func SampleUDLEntry() UDLEntry {
 	return UDLEntry{
+		Epoch:     time.Date(2009, 01, 02, 03, 04, 05, 06, time.UTC),
 		Timestamp: time.Date(2009, 01, 02, 03, 04, 05, 06, time.UTC).Format(udlTimeLayout),
 		Position: UDLPosition{
 			X: "854.324972",
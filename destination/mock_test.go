@@ -0,0 +1,152 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// Endpoints of mockClient used as keys of its failures.
+const (
+	mockAis   = "ais"
+	mockElset = "elset"
+	mockTLE   = "tle"
+	mockEphem = "ephem"
+	mockTuple = "tuple"
+)
+
+// mockFailure makes an endpoint of mockClient fail once at requests to it
+// were accepted. It fails with err or, if err is nil, with status and body.
+type mockFailure struct {
+	at     int
+	status int
+	body   string
+	err    error
+}
+
+// mockClient records the requests the destination makes to the UDL. Every
+// endpoint accepts all requests unless it has a failure configured in fail.
+type mockClient struct {
+	udl.ClientInterface
+	fail     map[string]mockFailure
+	accepted map[string]int
+
+	ais      []udl.AISIngest
+	aisSizes []int
+	elsets   []udl.ElsetIngest
+
+	tleParams []udl.CreateBulkFromTLEParams
+	tleBodies []string
+
+	ephemIDs    []string
+	ephemParams []udl.FiledropEphemPostIdParams
+	ephemBodies []string
+
+	// tupleBody is the response body of current elset tuple queries
+	tupleBody    string
+	tupleQueries []string
+}
+
+// failed returns the failure of the next request to endpoint, or neither a
+// response nor an error if the request is accepted.
+func (c *mockClient) failed(endpoint string) (*http.Response, error) {
+	f, ok := c.fail[endpoint]
+	if !ok || c.accepted[endpoint] < f.at {
+		if c.accepted == nil {
+			c.accepted = make(map[string]int)
+		}
+		c.accepted[endpoint]++
+		return nil, nil
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: f.status, Body: io.NopCloser(strings.NewReader(f.body))}, nil
+}
+
+func (c *mockClient) FiledropUdlAisPostId(ctx context.Context, body udl.FiledropUdlAisPostIdJSONRequestBody, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if resp, err := c.failed(mockAis); resp != nil || err != nil {
+		return resp, err
+	}
+	c.ais = append(c.ais, body...)
+	c.aisSizes = append(c.aisSizes, len(body))
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (c *mockClient) FiledropUdlElsetPostId(ctx context.Context, body udl.FiledropUdlElsetPostIdJSONRequestBody, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if resp, err := c.failed(mockElset); resp != nil || err != nil {
+		return resp, err
+	}
+	c.elsets = append(c.elsets, body...)
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (c *mockClient) CreateBulkFromTLEWithBody(ctx context.Context, params *udl.CreateBulkFromTLEParams, contentType string, body io.Reader, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if resp, err := c.failed(mockTLE); resp != nil || err != nil {
+		return resp, err
+	}
+	b, _ := io.ReadAll(body)
+	c.tleParams = append(c.tleParams, *params)
+	c.tleBodies = append(c.tleBodies, string(b))
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (c *mockClient) FiledropEphemPostIdWithBody(ctx context.Context, params *udl.FiledropEphemPostIdParams, contentType string, body io.Reader, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if resp, err := c.failed(mockEphem); resp != nil || err != nil {
+		return resp, err
+	}
+	b, _ := io.ReadAll(body)
+	c.ephemIDs = append(c.ephemIDs, params.IdOnOrbit)
+	c.ephemParams = append(c.ephemParams, *params)
+	c.ephemBodies = append(c.ephemBodies, string(b))
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (c *mockClient) CurrentTuple(ctx context.Context, params *udl.CurrentTupleParams, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, _ := http.NewRequest(http.MethodGet, "https://udl.test/udl/currentelset/tuple?columns="+params.Columns, nil)
+	for _, e := range reqEditors {
+		if err := e(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	c.tupleQueries = append(c.tupleQueries, req.URL.RawQuery)
+	if resp, err := c.failed(mockTuple); resp != nil || err != nil {
+		return resp, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(mockPage(c.tupleBody, req.URL.Query())))}, nil
+}
+
+// mockPage returns the page of the JSON array body selected by the
+// firstResult and maxResults parameters of q, the whole body without them.
+func mockPage(body string, q url.Values) string {
+	if !q.Has("maxResults") {
+		return body
+	}
+	var rows []json.RawMessage
+	_ = json.Unmarshal([]byte(body), &rows)
+	offset, _ := strconv.Atoi(q.Get("firstResult"))
+	limit, _ := strconv.Atoi(q.Get("maxResults"))
+	offset = min(offset, len(rows))
+	page, _ := json.Marshal(rows[offset:min(offset+limit, len(rows))])
+	return string(page)
+}
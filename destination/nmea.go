@@ -0,0 +1,557 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	aisFormatAuto  = "auto"
+	aisFormatSpire = "spire"
+	aisFormatNMEA  = "nmea"
+
+	knotsToKmh = 1.852
+)
+
+// aisNavStatus maps the AIS navigational status to the USCG NAVCEN name.
+var aisNavStatus = map[int]string{
+	0:  "Underway Using Engine",
+	1:  "At Anchor",
+	2:  "Not Under Command",
+	3:  "Restricted Maneuverability",
+	4:  "Constrained By Her Draught",
+	5:  "Moored",
+	6:  "Aground",
+	7:  "Engaged In Fishing",
+	8:  "Underway Sailing",
+	14: "AIS-SART Active",
+}
+
+// aisDeviceType maps the AIS type of electronic position fixing device to
+// its name.
+var aisDeviceType = map[int]string{
+	1:  "GPS",
+	2:  "GLONASS",
+	3:  "Combined GPS/GLONASS",
+	4:  "Loran-C",
+	5:  "Chayka",
+	6:  "Integrated Navigation System",
+	7:  "Surveyed",
+	8:  "Galileo",
+	15: "Internal GNSS",
+}
+
+// aisShipType is a ship type of the AIS ship and cargo type code in the
+// terms of the Spire ship types, so it maps through the same tables.
+type aisShipType struct {
+	shipType    string
+	shipSubType string
+}
+
+// aisShipTypeFor returns the Spire ship type of the AIS ship and cargo type
+// code, false if the type is not available.
+func aisShipTypeFor(code int) (aisShipType, bool) {
+	switch {
+	case code == 0:
+		return aisShipType{}, false
+	case code == 30:
+		return aisShipType{shipType: "FISHING"}, true
+	case code == 31, code == 32, code == 52:
+		return aisShipType{shipType: "TUG"}, true
+	case code == 33:
+		return aisShipType{shipType: "DREDGER", shipSubType: "Dredging Or Underwater Ops"}, true
+	case code == 34:
+		return aisShipType{shipType: "DIVE VESSEL", shipSubType: "Diving Ops"}, true
+	case code == 35:
+		return aisShipType{shipType: "MILITARY OPS", shipSubType: "Military Ops"}, true
+	case code == 36:
+		return aisShipType{shipType: "SAILING", shipSubType: "Sailing"}, true
+	case code == 37:
+		return aisShipType{shipType: "PLEASURE CRAFT", shipSubType: "Pleasure Craft"}, true
+	case code >= 40 && code <= 49:
+		return aisShipType{shipType: "HIGH SPEED CRAFT", shipSubType: "High Speed Craft"}, true
+	case code == 50:
+		return aisShipType{shipType: "PILOT VESSEL"}, true
+	case code == 51:
+		return aisShipType{shipType: "SEARCH AND RESCUE"}, true
+	case code == 53:
+		return aisShipType{shipType: "PORT TENDER"}, true
+	case code == 54:
+		return aisShipType{shipType: "ANTI POLLUTION", shipSubType: "Anti Pollution"}, true
+	case code == 55:
+		return aisShipType{shipType: "LAW ENFORCEMENT"}, true
+	case code == 58:
+		return aisShipType{shipType: "MEDICAL TRANS"}, true
+	case code >= 56 && code <= 59:
+		return aisShipType{shipType: "SPECIAL CRAFT"}, true
+	case code >= 60 && code <= 69:
+		return aisShipType{shipType: "PASSENGER"}, true
+	case code >= 70 && code <= 79:
+		return aisShipType{shipType: "GENERAL CARGO"}, true
+	case code >= 80 && code <= 89:
+		return aisShipType{shipType: "GENERAL TANKER"}, true
+	default:
+		return aisShipType{shipType: "OTHER"}, true
+	}
+}
+
+// detectAISFormat tells NMEA sentences, starting with ! or a \ tag block,
+// apart from Spire vessel JSON.
+func detectAISFormat(raw []byte) string {
+	s := strings.TrimSpace(string(raw))
+	if strings.HasPrefix(s, "!") || strings.HasPrefix(s, `\`) {
+		return aisFormatNMEA
+	}
+	return aisFormatSpire
+}
+
+// nmeaSentence is a single AIVDM or AIVDO sentence.
+type nmeaSentence struct {
+	count   int
+	num     int
+	seqID   string
+	channel string
+	payload string
+	fill    int
+	// ts is the time of the c tag of the tag block, if any
+	ts time.Time
+}
+
+// parseNMEASentence parses a sentence with an optional tag block and verifies
+// its checksums.
+func parseNMEASentence(line string) (nmeaSentence, error) {
+	var s nmeaSentence
+	if strings.HasPrefix(line, `\`) {
+		end := strings.Index(line[1:], `\`)
+		if end < 0 {
+			return s, fmt.Errorf("unterminated tag block: %s", line)
+		}
+		tags, err := checkNMEAChecksum(line[1 : end+1])
+		if err != nil {
+			return s, fmt.Errorf("tag block: %w", err)
+		}
+		for _, tag := range strings.Split(tags, ",") {
+			if k, v, ok := strings.Cut(tag, ":"); ok && k == "c" {
+				sec, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					return s, fmt.Errorf("invalid tag block time %q", v)
+				}
+				// some receivers report milliseconds
+				if sec > 1e11 {
+					s.ts = time.UnixMilli(sec).UTC()
+				} else {
+					s.ts = time.Unix(sec, 0).UTC()
+				}
+			}
+		}
+		line = line[end+2:]
+	}
+
+	if len(line) < 7 || line[0] != '!' || (line[3:6] != "VDM" && line[3:6] != "VDO") {
+		return s, fmt.Errorf("not an AIVDM or AIVDO sentence: %s", line)
+	}
+	body, err := checkNMEAChecksum(line[1:])
+	if err != nil {
+		return s, err
+	}
+	fields := strings.Split(body, ",")
+	if len(fields) != 7 {
+		return s, fmt.Errorf("expected 7 fields, got %d: %s", len(fields), line)
+	}
+	if s.count, err = strconv.Atoi(fields[1]); err != nil || s.count < 1 {
+		return s, fmt.Errorf("invalid fragment count %q", fields[1])
+	}
+	if s.num, err = strconv.Atoi(fields[2]); err != nil || s.num < 1 || s.num > s.count {
+		return s, fmt.Errorf("invalid fragment number %q", fields[2])
+	}
+	s.seqID, s.channel, s.payload = fields[3], fields[4], fields[5]
+	if s.fill, err = strconv.Atoi(fields[6]); err != nil || s.fill < 0 || s.fill > 5 {
+		return s, fmt.Errorf("invalid fill bits %q", fields[6])
+	}
+	return s, nil
+}
+
+// checkNMEAChecksum verifies the hex checksum following the * of s, the XOR
+// of all characters in front of it, and returns the checked characters.
+func checkNMEAChecksum(s string) (string, error) {
+	body, sum, ok := strings.Cut(s, "*")
+	if !ok || len(sum) < 2 {
+		return "", fmt.Errorf("missing checksum: %s", s)
+	}
+	want, err := strconv.ParseUint(sum[:2], 16, 8)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum %q", sum[:2])
+	}
+	var got byte
+	for i := 0; i < len(body); i++ {
+		got ^= body[i]
+	}
+	if got != byte(want) {
+		return "", fmt.Errorf("checksum %02X does not match %02X: %s", want, got, s)
+	}
+	return body, nil
+}
+
+// nmeaAssembler reassembles multi-sentence messages. All fragments of a
+// message have to be in the same record, a record is only acknowledged once
+// the messages decoded from it were submitted.
+type nmeaAssembler struct {
+	// pending holds the fragments received so far by channel and sequence ID
+	pending map[string][]nmeaSentence
+}
+
+func newNMEAAssembler() *nmeaAssembler {
+	return &nmeaAssembler{pending: make(map[string][]nmeaSentence)}
+}
+
+// add returns the complete message once its last fragment was added. It fails
+// on a fragment whose predecessors were not added.
+func (a *nmeaAssembler) add(s nmeaSentence) (nmeaSentence, bool, error) {
+	if s.count == 1 {
+		return s, true, nil
+	}
+	key := s.channel + "/" + s.seqID
+	fragments := a.pending[key]
+	if s.num == 1 {
+		// a new message replaces an incomplete one with the same ID
+		fragments = nil
+	}
+	if len(fragments) != s.num-1 {
+		return nmeaSentence{}, false, fmt.Errorf("fragment %d of %d of message %q without fragment %d", s.num, s.count, s.seqID, len(fragments)+1)
+	}
+	fragments = append(fragments, s)
+	if s.num < s.count {
+		a.pending[key] = fragments
+		return nmeaSentence{}, false, nil
+	}
+	delete(a.pending, key)
+
+	msg := fragments[0]
+	for _, f := range fragments[1:] {
+		msg.payload += f.payload
+	}
+	msg.fill = s.fill
+	msg.count, msg.num = 1, 1
+	return msg, true, nil
+}
+
+// incomplete returns an error for the first message still missing fragments.
+func (a *nmeaAssembler) incomplete() error {
+	for _, fragments := range a.pending {
+		last := fragments[len(fragments)-1]
+		return fmt.Errorf("message %q ends after fragment %d of %d", last.seqID, last.num, last.count)
+	}
+	return nil
+}
+
+// ToUDLAisNMEA decodes the AIVDM and AIVDO sentences of a record, one per
+// line. Message types 1, 2, 3, 5, 18, 19 and 24 are returned, other types are
+// skipped. Messages without a tag block time are reported at received. A
+// multi-sentence message has to be complete within the record.
+func ToUDLAisNMEA(raw []byte, shipTypes *ShipTypeMapping, received time.Time, dataMode udl.AISIngestDataMode, classificationMarking, source string) ([]udl.AISIngest, error) {
+	a := newNMEAAssembler()
+	var out []udl.AISIngest
+	for i, line := range splitLines(raw) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		s, err := parseNMEASentence(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		msg, ok, err := a.add(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if !ok {
+			continue
+		}
+
+		ais, ok, err := decodeAIS(msg.payload, msg.fill, shipTypes)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if !ok {
+			continue
+		}
+		ais.Ts = received
+		if !msg.ts.IsZero() {
+			ais.Ts = msg.ts
+		}
+		if ais.DestinationETA != nil {
+			eta := etaAfter(*ais.DestinationETA, ais.Ts)
+			ais.DestinationETA = &eta
+		}
+		ais.ClassificationMarking = classificationMarking
+		ais.DataMode = dataMode
+		ais.Source = source
+		out = append(out, ais)
+	}
+	if err := a.incomplete(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// etaAfter moves an ETA, which AIS reports without year, into the year that
+// puts it closest to ts.
+func etaAfter(eta, ts time.Time) time.Time {
+	eta = time.Date(ts.Year(), eta.Month(), eta.Day(), eta.Hour(), eta.Minute(), 0, 0, time.UTC)
+	if ts.Sub(eta) > 182*24*time.Hour {
+		eta = eta.AddDate(1, 0, 0)
+	}
+	return eta
+}
+
+// decodeAIS decodes an AIS message payload. It returns false for message
+// types that are not supported.
+func decodeAIS(payload string, fill int, shipTypes *ShipTypeMapping) (udl.AISIngest, bool, error) {
+	b, err := newAISBits(payload, fill)
+	if err != nil {
+		return udl.AISIngest{}, false, err
+	}
+
+	var ais udl.AISIngest
+	msgType := b.uint(0, 6)
+	mmsi := int64(b.uint(8, 30))
+	switch msgType {
+	case 1, 2, 3:
+		decodeAISPosition(b, &ais, aisPositionLayout{status: 38, rot: 42, sog: 50, accuracy: 60, lon: 61, lat: 89, cog: 116, heading: 128, maneuver: 143})
+	case 5:
+		if imo := b.uint(40, 30); imo != 0 {
+			imon := int64(imo)
+			ais.Imon = &imon
+		}
+		setAISString(&ais.CallSign, b.str(70, 42))
+		setAISString(&ais.ShipName, b.str(112, 120))
+		if err := setAISShipType(&ais, shipTypes, b.uint(232, 8)); err != nil {
+			return udl.AISIngest{}, false, err
+		}
+		setAISDimensions(&ais, b, 240, mmsi)
+		setAISDevice(&ais, b.uint(270, 4))
+		month, day, hour, minute := b.uint(274, 4), b.uint(278, 5), b.uint(283, 5), b.uint(288, 6)
+		if month >= 1 && month <= 12 && day >= 1 && hour < 24 && minute < 60 {
+			// the year is set once the message time is known
+			eta := time.Date(0, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+			ais.DestinationETA = &eta
+		}
+		if draught := b.uint(294, 8); draught != 0 {
+			d := float64(draught) / 10
+			ais.Draught = &d
+		}
+		setAISString(&ais.Destination, b.str(302, 120))
+	case 18:
+		decodeAISPosition(b, &ais, aisPositionLayout{status: -1, rot: -1, sog: 46, accuracy: 56, lon: 57, lat: 85, cog: 112, heading: 124, maneuver: -1})
+	case 19:
+		decodeAISPosition(b, &ais, aisPositionLayout{status: -1, rot: -1, sog: 46, accuracy: 56, lon: 57, lat: 85, cog: 112, heading: 124, maneuver: -1})
+		setAISString(&ais.ShipName, b.str(143, 120))
+		if err := setAISShipType(&ais, shipTypes, b.uint(263, 8)); err != nil {
+			return udl.AISIngest{}, false, err
+		}
+		setAISDimensions(&ais, b, 271, mmsi)
+		setAISDevice(&ais, b.uint(301, 4))
+	case 24:
+		switch b.uint(38, 2) {
+		case 0:
+			setAISString(&ais.ShipName, b.str(40, 120))
+		case 1:
+			if err := setAISShipType(&ais, shipTypes, b.uint(40, 8)); err != nil {
+				return udl.AISIngest{}, false, err
+			}
+			setAISString(&ais.CallSign, b.str(90, 42))
+			setAISDimensions(&ais, b, 132, mmsi)
+		default:
+			return udl.AISIngest{}, false, fmt.Errorf("invalid message 24 part %d", b.uint(38, 2))
+		}
+	default:
+		sdk.Logger(context.Background()).Debug().Msgf("skipping AIS message type %d", msgType)
+		return udl.AISIngest{}, false, nil
+	}
+	if b.err != nil {
+		return udl.AISIngest{}, false, fmt.Errorf("AIS message type %d: %w", msgType, b.err)
+	}
+	ais.Mmsi = &mmsi
+	return ais, true, nil
+}
+
+// aisPositionLayout holds the bit offsets of the position report fields, -1
+// for fields the message type does not have.
+type aisPositionLayout struct {
+	status, rot, sog, accuracy, lon, lat, cog, heading, maneuver int
+}
+
+func decodeAISPosition(b *aisBits, ais *udl.AISIngest, l aisPositionLayout) {
+	if l.status >= 0 {
+		if status, ok := aisNavStatus[b.uint(l.status, 4)]; ok {
+			ais.NavStatus = &status
+		}
+	}
+	// -128 is not available, +-127 turning faster than 5 degrees per 30s
+	// without a rate
+	if l.rot >= 0 {
+		if rot := b.int(l.rot, 8); rot > -127 && rot < 127 {
+			rate := math.Copysign(math.Pow(float64(rot)/4.733, 2), float64(rot))
+			ais.RateOfTurn = &rate
+		}
+	}
+	if sog := b.uint(l.sog, 10); sog != 1023 {
+		speed := float64(sog) / 10 * knotsToKmh
+		ais.Speed = &speed
+	}
+	hiAccuracy := b.uint(l.accuracy, 1) == 1
+	ais.PosHiAccuracy = &hiAccuracy
+	if lon := b.int(l.lon, 28); lon != 181*600000 {
+		v := float64(lon) / 600000
+		ais.Lon = &v
+	}
+	if lat := b.int(l.lat, 27); lat != 91*600000 {
+		v := float64(lat) / 600000
+		ais.Lat = &v
+	}
+	if cog := b.uint(l.cog, 12); cog < 3600 {
+		v := float64(cog) / 10
+		ais.Course = &v
+	}
+	if heading := b.uint(l.heading, 9); heading < 360 {
+		v := float64(heading)
+		ais.TrueHeading = &v
+	}
+	if l.maneuver >= 0 {
+		if m := b.uint(l.maneuver, 2); m == 1 || m == 2 {
+			special := m == 2
+			ais.SpecialManeuver = &special
+		}
+	}
+}
+
+func setAISString(field **string, v string) {
+	if v != "" {
+		*field = &v
+	}
+}
+
+// setAISShipType maps the AIS ship and cargo type code through the Spire ship
+// type mappings.
+func setAISShipType(ais *udl.AISIngest, shipTypes *ShipTypeMapping, code int) error {
+	t, ok := aisShipTypeFor(code)
+	if !ok {
+		return nil
+	}
+	return shipTypes.setAISShipType(t.shipType, t.shipSubType, ais)
+}
+
+// setAISDimensions sets the antenna reference dimensions starting at bit
+// offset, which auxiliary craft use for the MMSI of their mother ship.
+func setAISDimensions(ais *udl.AISIngest, b *aisBits, offset int, mmsi int64) {
+	if mmsi/10000000 == 98 {
+		return
+	}
+	dims := []float64{
+		float64(b.uint(offset, 9)),
+		float64(b.uint(offset+9, 9)),
+		float64(b.uint(offset+18, 6)),
+		float64(b.uint(offset+24, 6)),
+	}
+	if allZero(dims) {
+		return
+	}
+	ais.AntennaRefDimensions = &dims
+	if length := dims[0] + dims[1]; length != 0 {
+		ais.Length = &length
+	}
+	if width := dims[2] + dims[3]; width != 0 {
+		ais.Width = &width
+	}
+}
+
+func setAISDevice(ais *udl.AISIngest, code int) {
+	if device, ok := aisDeviceType[code]; ok {
+		ais.PosDeviceType = &device
+	}
+}
+
+// aisBits reads fields of the bit vector of an AIS payload and keeps the
+// first error.
+type aisBits struct {
+	bits []byte
+	err  error
+}
+
+// newAISBits dearmors the 6-bit ASCII payload.
+func newAISBits(payload string, fill int) (*aisBits, error) {
+	bits := make([]byte, 0, len(payload)*6)
+	for i := 0; i < len(payload); i++ {
+		c := payload[i]
+		if c < '0' || c > 'w' || (c > 'W' && c < '`') {
+			return nil, fmt.Errorf("invalid AIS payload character %q", c)
+		}
+		v := c - '0'
+		if v > 40 {
+			v -= 8
+		}
+		for k := 5; k >= 0; k-- {
+			bits = append(bits, (v>>k)&1)
+		}
+	}
+	if fill > len(bits) {
+		return nil, fmt.Errorf("%d fill bits exceed the payload", fill)
+	}
+	return &aisBits{bits: bits[:len(bits)-fill]}, nil
+}
+
+func (b *aisBits) uint(start, n int) int {
+	if start+n > len(b.bits) {
+		if b.err == nil {
+			b.err = fmt.Errorf("message of %d bits too short for field at bit %d", len(b.bits), start)
+		}
+		return 0
+	}
+	v := 0
+	for _, bit := range b.bits[start : start+n] {
+		v = v<<1 | int(bit)
+	}
+	return v
+}
+
+func (b *aisBits) int(start, n int) int {
+	v := b.uint(start, n)
+	if v&(1<<(n-1)) != 0 {
+		v -= 1 << n
+	}
+	return v
+}
+
+// str reads 6-bit ASCII text without the trailing @ padding and spaces.
+func (b *aisBits) str(start, n int) string {
+	var sb strings.Builder
+	for i := start; i+6 <= start+n; i += 6 {
+		c := b.uint(i, 6)
+		if c < 32 {
+			c += 64
+		}
+		sb.WriteByte(byte(c))
+	}
+	return strings.TrimRight(sb.String(), "@ ")
+}
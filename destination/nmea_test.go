@@ -0,0 +1,171 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	nmeaType1     = "!AIVDM,1,1,,A,15RTgt0PAso;90TKcjM8h6g208CQ,0*4A"
+	nmeaType5Part = "!AIVDM,2,1,1,A,55?MbV02;H;s<HtKR20EHE:0@T4@Dn2222222216L961O5Gf0NSQEp6ClRp8,0*1C"
+	nmeaType5End  = "!AIVDM,2,2,1,A,88888888880,2*25"
+	nmeaType18    = "!AIVDM,1,1,,B,B5NJ;PP005l4ot5Isbl03wsUkP06,0*75"
+	nmeaType24A   = "!AIVDM,1,1,,A,H42O55i18tMET00000000000000,2*6D"
+	nmeaType24B   = "!AIVDM,1,1,,B,H42O55lti4hhhilD3nink000?050,0*43"
+	nmeaType4     = "!AIVDM,1,1,,A,402M3b@000Htt0K0h0:D3q000000,0*1B"
+)
+
+var nmeaReceived = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestToUDLAisNMEA(t *testing.T) {
+	is := is.New(t)
+
+	raw := nmeaType1 + "\n" + nmeaType5Part + "\n" + nmeaType5End + "\n" + nmeaType18 + "\n" + nmeaType24A + "\n" + nmeaType24B + "\n" + nmeaType4
+	out, err := ToUDLAisNMEA([]byte(raw), defaultShipTypes, nmeaReceived, udl.AISIngestDataModeTEST, "U", "Spire")
+	is.NoErr(err)
+	is.Equal(len(out), 5) // the base station report is skipped
+
+	position := out[0]
+	is.Equal(*position.Mmsi, int64(371798000))
+	is.Equal(position.Ts, nmeaReceived)
+	is.Equal(position.DataMode, udl.AISIngestDataModeTEST)
+	is.Equal(position.ClassificationMarking, "U")
+	is.Equal(*position.NavStatus, "Underway Using Engine")
+	is.True(math.Abs(*position.Lat-48.381633) < 1e-6)
+	is.True(math.Abs(*position.Lon+123.395383) < 1e-6)
+	is.True(math.Abs(*position.Speed-12.3*knotsToKmh) < 1e-9)
+	is.Equal(*position.Course, 224.0)
+	is.Equal(*position.TrueHeading, 215.0)
+	is.Equal(*position.PosHiAccuracy, true)
+	is.Equal(position.RateOfTurn, nil) // turning without rate
+
+	static := out[1]
+	is.Equal(*static.Mmsi, int64(351759000))
+	is.Equal(*static.Imon, int64(9134270))
+	is.Equal(*static.CallSign, "3FOF8")
+	is.Equal(*static.ShipName, "EVER DIADEM")
+	is.Equal(*static.ShipType, "Cargo")
+	is.Equal(*static.CargoType, "General Cargo")
+	is.Equal(*static.AntennaRefDimensions, []float64{225, 70, 1, 31})
+	is.Equal(*static.Length, 295.0)
+	is.Equal(*static.Width, 32.0)
+	is.Equal(*static.PosDeviceType, "GPS")
+	is.Equal(*static.DestinationETA, time.Date(2023, 5, 15, 14, 0, 0, 0, time.UTC))
+	is.Equal(*static.Draught, 12.2)
+	is.Equal(*static.Destination, "NEW YORK")
+
+	classB := out[2]
+	is.Equal(*classB.Mmsi, int64(367430530))
+	is.True(math.Abs(*classB.Lat-37.785035) < 1e-6)
+	is.True(math.Abs(*classB.Lon+122.26732) < 1e-6)
+	is.Equal(*classB.Speed, 0.0)
+	is.Equal(classB.TrueHeading, nil)
+
+	is.Equal(*out[3].ShipName, "PROGUY")
+	is.Equal(*out[4].ShipType, "Passenger")
+	is.Equal(*out[4].CallSign, "TC6163")
+	is.Equal(*out[4].AntennaRefDimensions, []float64{0, 15, 0, 5})
+}
+
+func TestToUDLAisNMEA_FragmentsAcrossRecords(t *testing.T) {
+	is := is.New(t)
+
+	// all fragments of a message have to be in the same record
+	_, err := ToUDLAisNMEA([]byte(nmeaType5Part), defaultShipTypes, nmeaReceived, udl.AISIngestDataModeTEST, "U", "Spire")
+	is.True(err != nil)
+
+	_, err = ToUDLAisNMEA([]byte(nmeaType5End), defaultShipTypes, nmeaReceived, udl.AISIngestDataModeTEST, "U", "Spire")
+	is.True(err != nil)
+}
+
+func TestToUDLAisNMEA_TagBlockTime(t *testing.T) {
+	is := is.New(t)
+
+	raw := `\s:r003669945,c:1241544035*79\` + nmeaType1
+	out, err := ToUDLAisNMEA([]byte(raw), defaultShipTypes, nmeaReceived, udl.AISIngestDataModeTEST, "U", "Spire")
+	is.NoErr(err)
+	is.Equal(out[0].Ts, time.Unix(1241544035, 0).UTC())
+}
+
+func TestToUDLAisNMEA_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "checksum", raw: nmeaType1[:len(nmeaType1)-2] + "4B"},
+		{name: "missing checksum", raw: nmeaType1[:len(nmeaType1)-3]},
+		{name: "other sentence", raw: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"},
+		{name: "tag block checksum", raw: `\s:r003669945,c:1241544035*7A\` + nmeaType1},
+		{name: "short message", raw: "!AIVDM,1,1,,A,15RTgt0PAso;90,0*38"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := ToUDLAisNMEA([]byte(tt.raw), defaultShipTypes, nmeaReceived, udl.AISIngestDataModeTEST, "U", "Spire")
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestWrite_AisNMEA(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	dest.Config.NMEASource = "ExactEarth"
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType5Part + "\n" + nmeaType5End)}},
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType1)}},
+		aisRecord(),
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 3)
+	is.Equal(len(client.ais), 3)
+	is.Equal(*client.ais[0].ShipName, "EVER DIADEM")
+	is.Equal(*client.ais[1].Mmsi, int64(371798000))
+	is.Equal(*client.ais[2].Id, "1")
+	is.Equal(client.ais[0].Source, "ExactEarth")
+	is.Equal(client.ais[1].Source, "ExactEarth")
+	is.Equal(client.ais[2].Source, "Spire") // Spire vessel JSON keeps its source
+}
+
+func TestWrite_AisNMEASplitMessage(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType1)}},
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType5Part)}},
+		{Payload: sdk.Change{After: sdk.RawData(nmeaType5End)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.True(err != nil)
+	is.Equal(n, 1)
+	is.Equal(len(client.ais), 1) // only the record before the fragment is written
+	is.Equal(*client.ais[0].Mmsi, int64(371798000))
+}
@@ -0,0 +1,499 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// Sources of the NORAD IDs of Spire flight modules and satellite names.
+const (
+	noradSourceBuiltin = "builtin"
+	noradSourceFile    = "file"
+	noradSourceUDL     = "udl"
+)
+
+// What to do with a satellite whose name and flight module resolve to
+// different NORAD IDs.
+const (
+	nameMismatchReject = "reject"
+	nameMismatchFlag   = "flag"
+)
+
+const defaultNoradCacheTTL = time.Hour
+
+// udlCatalogPageSize is the number of current elsets fetched per request.
+const udlCatalogPageSize = 1000
+
+// errNoNoradMapping is returned by a catalog that does not know the key, so
+// the next catalog is asked.
+var errNoNoradMapping = errors.New("no norad mapping")
+
+// LookupError is returned when a catalog fails to look up a NORAD ID, e.g.
+// because the UDL is unreachable or the mapping file can not be read. Unlike
+// a missing mapping, it is not the record's fault, so the record is retried
+// instead of being handled as a transform error.
+type LookupError struct {
+	Err error
+}
+
+func (e *LookupError) Error() string {
+	return fmt.Sprintf("norad lookup failed: %v", e.Err)
+}
+
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+// lookupFailed wraps the error of a catalog lookup that failed for another
+// reason than a missing mapping.
+func lookupFailed(err error) error {
+	if err == nil || errors.Is(err, errNoNoradMapping) {
+		return err
+	}
+	return &LookupError{Err: err}
+}
+
+// catalog looks up the NORAD ID of a satellite by key, its flight module
+// number or its normalized name.
+type catalog[K comparable] interface {
+	NoradID(ctx context.Context, key K) (int, error)
+}
+
+// NoradResolver resolves the NORAD ID of a Spire satellite from its flight
+// module number. With name catalogs, the NORAD ID of the satellite name is
+// cross-checked against it and used for flight modules that are not known.
+type NoradResolver struct {
+	flightModules catalog[int]
+	names         catalog[string]
+	// satellites resolves SP3 satellite IDs, it is nil unless configured
+	satellites catalog[string]
+	mismatch   string
+}
+
+// defaultNoradResolver resolves flight modules through the built-in table.
+var defaultNoradResolver = &NoradResolver{flightModules: builtinNorad{}}
+
+// NoradID returns the NORAD ID of the satellite. If its name resolves to a
+// different NORAD ID, the satellite is rejected or, with the flag mismatch
+// policy, the NORAD ID of the flight module is returned with a note on the
+// mismatch.
+func (r *NoradResolver) NoradID(ctx context.Context, fm int, name string) (int, string, error) {
+	id, err := r.flightModules.NoradID(ctx, fm)
+	if err = lookupFailed(err); err != nil && !errors.Is(err, errNoNoradMapping) {
+		return 0, "", err
+	}
+	if r.names == nil || satelliteNameKey(name) == "" {
+		return id, "", err
+	}
+
+	nameID, nameErr := r.names.NoradID(ctx, satelliteNameKey(name))
+	nameErr = lookupFailed(nameErr)
+	switch {
+	case nameErr != nil && !errors.Is(nameErr, errNoNoradMapping):
+		return 0, "", nameErr
+	case nameErr != nil:
+		return id, "", err
+	case err != nil:
+		return nameID, "", nil
+	case nameID == id:
+		return id, "", nil
+	}
+
+	mismatch := fmt.Sprintf("satellite name %s is NORAD ID %d, flight module %d is NORAD ID %d", name, nameID, fm, id)
+	if r.mismatch == nameMismatchFlag {
+		sdk.Logger(ctx).Warn().Msg(mismatch)
+		return id, mismatch, nil
+	}
+	return 0, "", errors.New(mismatch)
+}
+
+// SP3NoradID returns the NORAD ID of an SP3 satellite. A satellite listed by
+// its SP3 ID, e.g. G01, is resolved by it, any other by its flight module and
+// name as in NoradID.
+func (r *NoradResolver) SP3NoradID(ctx context.Context, satelliteID string, fm int, name string) (int, string, error) {
+	if r.satellites != nil {
+		id, err := r.satellites.NoradID(ctx, sp3SatelliteKey(satelliteID))
+		if !errors.Is(err, errNoNoradMapping) {
+			return id, "", lookupFailed(err)
+		}
+	}
+	return r.NoradID(ctx, fm, name)
+}
+
+// ObjectNoradID returns the NORAD ID of an OEM object. Its OBJECT_ID is the
+// NORAD ID itself or, more often, an international designator such as
+// 1998-067A, which is resolved through the name catalogs like its
+// OBJECT_NAME.
+func (r *NoradResolver) ObjectNoradID(ctx context.Context, objectID, name string) (int, error) {
+	if id, err := strconv.Atoi(strings.TrimSpace(objectID)); err == nil && id > 0 {
+		return id, nil
+	}
+	if r.names == nil {
+		return 0, errNoNoradMapping
+	}
+	for _, key := range []string{objectID, name} {
+		if satelliteNameKey(key) == "" {
+			continue
+		}
+		id, err := r.names.NoradID(ctx, satelliteNameKey(key))
+		if !errors.Is(err, errNoNoradMapping) {
+			return id, lookupFailed(err)
+		}
+	}
+	return 0, errNoNoradMapping
+}
+
+// builtinNorad resolves flight modules through the generated fmMap.
+type builtinNorad struct{}
+
+var builtinFMNorad = fmMap()
+
+func (builtinNorad) NoradID(_ context.Context, fm int) (int, error) {
+	if id, ok := builtinFMNorad[fm]; ok {
+		return id, nil
+	}
+	return 0, errNoNoradMapping
+}
+
+// fileCatalog resolves keys through a JSON file mapping them to NORAD IDs,
+// e.g. {"144": 46502}. The file is read again when its modification time or
+// size changes.
+type fileCatalog[K comparable] struct {
+	path string
+	key  func(string) (K, bool)
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	ids     map[K]int
+}
+
+func (f *fileCatalog[K]) NoradID(_ context.Context, key K) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.reload(); err != nil {
+		return 0, err
+	}
+	if id, ok := f.ids[key]; ok {
+		return id, nil
+	}
+	return 0, errNoNoradMapping
+}
+
+// reload reads the file if it changed since it was last read.
+func (f *fileCatalog[K]) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("norad mapping file: %w", err)
+	}
+	if f.ids != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("norad mapping file: %w", err)
+	}
+	var mapping map[string]int
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return fmt.Errorf("norad mapping file %s: %w", f.path, err)
+	}
+	ids := make(map[K]int, len(mapping))
+	for k, v := range mapping {
+		key, ok := f.key(k)
+		if !ok {
+			return fmt.Errorf("norad mapping file %s: invalid key %q", f.path, k)
+		}
+		ids[key] = v
+	}
+	f.ids, f.modTime, f.size = ids, info.ModTime(), info.Size()
+	return nil
+}
+
+// udlCatalog resolves keys through the current elsets in the UDL, which are
+// fetched at most once per ttl.
+type udlCatalog[K comparable] struct {
+	client udl.ClientInterface
+	// columns are the current elset fields fetched, query the additional
+	// query parameters
+	columns string
+	query   map[string]string
+	// keys returns the keys of an elset
+	keys     func(udl.ElsetFull) []K
+	ttl      time.Duration
+	now      func() time.Time
+	pageSize int
+
+	mu      sync.Mutex
+	fetched time.Time
+	ids     map[K]int
+}
+
+func (u *udlCatalog[K]) NoradID(ctx context.Context, key K) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.ids == nil || u.now().Sub(u.fetched) >= u.ttl {
+		ids, err := u.fetch(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("current elsets: %w", err)
+		}
+		u.ids, u.fetched = ids, u.now()
+	}
+	if id, ok := u.ids[key]; ok {
+		return id, nil
+	}
+	return 0, errNoNoradMapping
+}
+
+// fetch returns the keys of the current elsets. They are paged through in
+// the order of their idElset, so a server side limit on the rows of a
+// response does not cut the catalog short.
+func (u *udlCatalog[K]) fetch(ctx context.Context) (map[K]int, error) {
+	pageSize := u.pageSize
+	if pageSize <= 0 {
+		pageSize = udlCatalogPageSize
+	}
+	ids := make(map[K]int)
+	for offset := 0; ; offset += pageSize {
+		elsets, err := u.fetchPage(ctx, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range elsets {
+			if e.SatNo == nil {
+				continue
+			}
+			for _, k := range u.keys(e) {
+				ids[k] = int(*e.SatNo)
+			}
+		}
+		if len(elsets) < pageSize {
+			return ids, nil
+		}
+	}
+}
+
+// fetchPage returns limit current elsets starting at offset.
+func (u *udlCatalog[K]) fetchPage(ctx context.Context, offset, limit int) ([]udl.ElsetFull, error) {
+	query := map[string]string{
+		"firstResult": strconv.Itoa(offset),
+		"maxResults":  strconv.Itoa(limit),
+	}
+	for k, v := range u.query {
+		query[k] = v
+	}
+	resp, err := u.client.CurrentTuple(ctx, &udl.CurrentTupleParams{Columns: u.columns}, udl.WithQuery(query), udl.WithSort("idElset"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, responseError(resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var elsets []udl.ElsetFull
+	if err := json.Unmarshal(body, &elsets); err != nil {
+		return nil, err
+	}
+	return elsets, nil
+}
+
+// udlFlightModules resolves flight modules through the current elsets of a
+// source, whose origObjectId holds the flight module number and satNo the
+// NORAD ID. The UDL on-orbit catalog has no field for flight modules, and the
+// generated client has no on-orbit operations, so the elsets of the source
+// are used. It only knows the flight modules the source publishes its elsets
+// under; origObjectId values that are no flight module number are ignored.
+func udlFlightModules(client udl.ClientInterface, source string, ttl time.Duration) *udlCatalog[int] {
+	return &udlCatalog[int]{
+		client:  client,
+		columns: "satNo,origObjectId,source",
+		query:   map[string]string{"source": source},
+		keys: func(e udl.ElsetFull) []int {
+			if e.Source != source || e.OrigObjectId == nil {
+				return nil
+			}
+			if fm, ok := parseFlightModule(*e.OrigObjectId); ok {
+				return []int{fm}
+			}
+			return nil
+		},
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// udlSatelliteNames resolves satellite names through the common and
+// alternate names of the on-orbit objects of the current elsets, and
+// international designators through their intlDes.
+func udlSatelliteNames(client udl.ClientInterface, ttl time.Duration) *udlCatalog[string] {
+	return &udlCatalog[string]{
+		client:  client,
+		columns: "satNo,onOrbit",
+		keys: func(e udl.ElsetFull) []string {
+			if e.OnOrbit == nil {
+				return nil
+			}
+			var keys []string
+			for _, name := range []*string{e.OnOrbit.CommonName, e.OnOrbit.AltName, e.OnOrbit.IntlDes} {
+				if name != nil && satelliteNameKey(*name) != "" {
+					keys = append(keys, satelliteNameKey(*name))
+				}
+			}
+			return keys
+		},
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// parseFlightModule parses a flight module number, optionally prefixed with
+// FM, e.g. 144, FM144 or FM-144.
+func parseFlightModule(s string) (int, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimLeft(strings.TrimPrefix(s, "FM"), "- ")
+	fm, err := strconv.Atoi(s)
+	if err != nil || fm <= 0 {
+		return 0, false
+	}
+	return fm, true
+}
+
+// sp3SatelliteKey normalizes an SP3 satellite ID to upper case.
+func sp3SatelliteKey(id string) string {
+	return strings.ToUpper(strings.TrimSpace(id))
+}
+
+// satelliteNameKey normalizes a satellite name to its upper case letters and
+// digits, so LEMUR-2-JOHN-TREIRES matches Lemur 2 John Treires.
+func satelliteNameKey(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToUpper(name) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// catalogChain asks the catalogs in order until one knows the key.
+type catalogChain[K comparable] []catalog[K]
+
+func (c catalogChain[K]) NoradID(ctx context.Context, key K) (int, error) {
+	for _, r := range c {
+		id, err := r.NoradID(ctx, key)
+		if !errors.Is(err, errNoNoradMapping) {
+			return id, err
+		}
+	}
+	return 0, errNoNoradMapping
+}
+
+// splitSources returns the sources of a comma-separated list.
+func splitSources(list string) []string {
+	var sources []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// newNoradResolver returns the resolver of the configured flight module and
+// satellite name sources. Flight modules are resolved through the built-in
+// table by default, satellite names are not resolved by default.
+func newNoradResolver(cfg Config, client udl.ClientInterface) (*NoradResolver, error) {
+	ttl := cfg.NoradCacheTTL
+	if ttl == 0 {
+		ttl = defaultNoradCacheTTL
+	}
+
+	sources := splitSources(cfg.NoradSources)
+	if len(sources) == 0 {
+		sources = []string{noradSourceBuiltin}
+	}
+	var flightModules catalogChain[int]
+	for _, s := range sources {
+		switch s {
+		case noradSourceBuiltin:
+			flightModules = append(flightModules, builtinNorad{})
+		case noradSourceFile:
+			if cfg.NoradMappingFile == "" {
+				return nil, errors.New("norad source file requires noradMappingFile")
+			}
+			flightModules = append(flightModules, &fileCatalog[int]{path: cfg.NoradMappingFile, key: parseFlightModule})
+		case noradSourceUDL:
+			flightModules = append(flightModules, udlFlightModules(client, elsetSource(cfg), ttl))
+		default:
+			return nil, fmt.Errorf("unsupported norad source: %s", s)
+		}
+	}
+
+	var names catalogChain[string]
+	for _, s := range splitSources(cfg.SatelliteNameSources) {
+		switch s {
+		case noradSourceFile:
+			if cfg.SatelliteNameFile == "" {
+				return nil, errors.New("satellite name source file requires satelliteNameFile")
+			}
+			names = append(names, &fileCatalog[string]{path: cfg.SatelliteNameFile, key: func(s string) (string, bool) {
+				key := satelliteNameKey(s)
+				return key, key != ""
+			}})
+		case noradSourceUDL:
+			names = append(names, udlSatelliteNames(client, ttl))
+		default:
+			return nil, fmt.Errorf("unsupported satellite name source: %s", s)
+		}
+	}
+
+	mismatch := strings.ToLower(strings.TrimSpace(cfg.SatelliteNameMismatch))
+	switch mismatch {
+	case "":
+		mismatch = nameMismatchReject
+	case nameMismatchReject, nameMismatchFlag:
+	default:
+		return nil, fmt.Errorf("unsupported satellite name mismatch policy: %s", cfg.SatelliteNameMismatch)
+	}
+
+	r := &NoradResolver{flightModules: flightModules, mismatch: mismatch}
+	if len(names) > 0 {
+		r.names = names
+	}
+	if cfg.SP3SatelliteFile != "" {
+		r.satellites = &fileCatalog[string]{path: cfg.SP3SatelliteFile, key: func(s string) (string, bool) {
+			key := sp3SatelliteKey(s)
+			return key, key != ""
+		}}
+	}
+	return r, nil
+}
@@ -0,0 +1,375 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+)
+
+func writeNoradMapping(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "norad.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuiltinNorad(t *testing.T) {
+	is := is.New(t)
+
+	id, err := builtinNorad{}.NoradID(context.Background(), 144)
+	is.NoErr(err)
+	is.Equal(id, 46502)
+
+	_, err = builtinNorad{}.NoradID(context.Background(), 999)
+	is.True(errors.Is(err, errNoNoradMapping))
+}
+
+func TestFileCatalog_Reload(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := writeNoradMapping(t, `{"999": 55555, "FM1000": 55556}`)
+	f := &fileCatalog[int]{path: path, key: parseFlightModule}
+
+	id, err := f.NoradID(ctx, 999)
+	is.NoErr(err)
+	is.Equal(id, 55555)
+	id, err = f.NoradID(ctx, 1000)
+	is.NoErr(err)
+	is.Equal(id, 55556)
+
+	is.NoErr(os.WriteFile(path, []byte(`{"999": 66666}`), 0o600))
+	later := time.Now().Add(time.Minute)
+	is.NoErr(os.Chtimes(path, later, later))
+
+	id, err = f.NoradID(ctx, 999)
+	is.NoErr(err)
+	is.Equal(id, 66666)
+	_, err = f.NoradID(ctx, 1000)
+	is.True(errors.Is(err, errNoNoradMapping))
+}
+
+func TestFileCatalog_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.json")},
+		{name: "invalid json", path: writeNoradMapping(t, `{"999": "55555"}`)},
+		{name: "invalid flight module", path: writeNoradMapping(t, `{"LEMUR": 55555}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := (&fileCatalog[int]{path: tt.path, key: parseFlightModule}).NoradID(context.Background(), 999)
+			is.True(err != nil)
+			is.True(!errors.Is(err, errNoNoradMapping))
+		})
+	}
+}
+
+func TestUDLFlightModules_Cache(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := &mockClient{tupleBody: `[
+		{"satNo": 55555, "origObjectId": "FM999", "source": "Spire"},
+		{"satNo": 55556, "origObjectId": "1000", "source": "Spire"},
+		{"satNo": 55557, "origObjectId": "1001", "source": "Other"},
+		{"satNo": 55558, "source": "Spire"}
+	]`}
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	u := udlFlightModules(client, "Spire", time.Hour)
+	u.now = func() time.Time { return now }
+	u.pageSize = 3
+
+	id, err := u.NoradID(ctx, 999)
+	is.NoErr(err)
+	is.Equal(id, 55555)
+	id, err = u.NoradID(ctx, 1000)
+	is.NoErr(err)
+	is.Equal(id, 55556)
+	_, err = u.NoradID(ctx, 1001)
+	is.True(errors.Is(err, errNoNoradMapping))
+	is.Equal(client.tupleQueries, []string{ // paged until a page is not full
+		"columns=satNo%2CorigObjectId%2Csource&firstResult=0&maxResults=3&sort=idElset%2CASC&source=Spire",
+		"columns=satNo%2CorigObjectId%2Csource&firstResult=3&maxResults=3&sort=idElset%2CASC&source=Spire",
+	})
+
+	now = now.Add(time.Hour)
+	_, err = u.NoradID(ctx, 999)
+	is.NoErr(err)
+	is.Equal(len(client.tupleQueries), 4)
+}
+
+func TestUDLFlightModules_Error(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{
+		mockTuple: {status: http.StatusUnauthorized, body: "unauthorized"},
+	}}
+	u := udlFlightModules(client, "Spire", time.Hour)
+
+	_, err := u.NoradID(context.Background(), 999)
+	is.True(err != nil)
+	is.True(!errors.Is(err, errNoNoradMapping))
+}
+
+func TestWriteEphemeris_LookupError(t *testing.T) {
+	for _, policy := range TransformErrorPolicyValues {
+		t.Run(policy, func(t *testing.T) {
+			is := is.New(t)
+			client := &mockClient{fail: map[string]mockFailure{
+				mockTuple: {status: http.StatusServiceUnavailable, body: "unavailable"},
+			}}
+			dest := Destination{client: client}
+			dest.Config.DataType = "EPHEMERIS"
+			dest.Config.NoradSources = "udl"
+			dest.Config.TransformErrorPolicy = policy
+			norad, err := newNoradResolver(dest.Config, client)
+			is.NoErr(err)
+			dest.norad = norad
+
+			n, err := dest.Write(context.Background(), ephemerisRecords(2))
+			is.Equal(n, 0) // the records are retried, not skipped or dead-lettered
+			var lookupErr *LookupError
+			is.True(errors.As(err, &lookupErr))
+			var terr *TransformError
+			is.True(!errors.As(err, &terr))
+			is.Equal(len(client.ephemIDs), 0)
+		})
+	}
+}
+
+func TestParseFlightModule(t *testing.T) {
+	tests := []struct {
+		in   string
+		fm   int
+		isFM bool
+	}{
+		{in: "144", fm: 144, isFM: true},
+		{in: "FM144", fm: 144, isFM: true},
+		{in: "fm-144", fm: 144, isFM: true},
+		{in: " FM 5 ", fm: 5, isFM: true},
+		{in: "LEMUR-2", isFM: false},
+		{in: "FM0", isFM: false},
+		{in: "", isFM: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			is := is.New(t)
+			fm, ok := parseFlightModule(tt.in)
+			is.Equal(ok, tt.isFM)
+			is.Equal(fm, tt.fm)
+		})
+	}
+}
+
+func TestNewNoradResolver(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := writeNoradMapping(t, `{"144": 11111, "999": 55555}`)
+
+	// the file is asked first, the built-in table for the others
+	var cfg Config
+	cfg.NoradSources = "file, builtin"
+	cfg.NoradMappingFile = path
+	r, err := newNoradResolver(cfg, nil)
+	is.NoErr(err)
+	id, _, err := r.NoradID(ctx, 144, "")
+	is.NoErr(err)
+	is.Equal(id, 11111)
+	id, _, err = r.NoradID(ctx, 143, "")
+	is.NoErr(err)
+	is.Equal(id, 48925)
+	_, _, err = r.NoradID(ctx, 1000, "")
+	is.True(errors.Is(err, errNoNoradMapping))
+
+	r, err = newNoradResolver(Config{}, nil)
+	is.NoErr(err)
+	is.Equal(r.flightModules, catalogChain[int]{builtinNorad{}})
+	is.Equal(r.names, nil)
+}
+
+func TestNewNoradResolver_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  string
+		names    string
+		mismatch string
+	}{
+		{name: "unsupported source", sources: "builtin,celestrak"},
+		{name: "file without path", sources: "file"},
+		{name: "builtin satellite names", names: "builtin"},
+		{name: "satellite name file without path", names: "file"},
+		{name: "unsupported mismatch policy", mismatch: "ignore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var cfg Config
+			cfg.NoradSources = tt.sources
+			cfg.SatelliteNameSources = tt.names
+			cfg.SatelliteNameMismatch = tt.mismatch
+			_, err := newNoradResolver(cfg, nil)
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestWriteEphemeris_NoradFile(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.NoradSources = "builtin,file"
+	dest.Config.NoradMappingFile = writeNoradMapping(t, `{"999": 55555}`)
+	raw := bytes.ReplaceAll(sampleFileSP3d(), []byte("144"), []byte("999"))
+	norad, err := newNoradResolver(dest.Config, client)
+	is.NoErr(err)
+	dest.norad = norad
+
+	n, err := dest.Write(context.Background(), []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(raw)}}})
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(client.ephemIDs, []string{"48925", "55555"})
+}
+
+func TestNoradResolver_SatelliteName(t *testing.T) {
+	names := &fileCatalog[string]{
+		path: writeNoradMapping(t, `{"LEMUR-2-JOHN-TREIRES": 48925, "Lemur 2 Rocketgirl": 11111, "NEW-SAT": 55555}`),
+		key: func(s string) (string, bool) {
+			return satelliteNameKey(s), true
+		},
+	}
+	tests := []struct {
+		name     string
+		fm       int
+		satName  string
+		mismatch string
+		want     int
+		flagged  bool
+		wantErr  bool
+	}{
+		{name: "names agree", fm: 143, satName: "LEMUR-2-JOHN-TREIRES", want: 48925},
+		{name: "names agree normalized", fm: 143, satName: "lemur 2 john treires", want: 48925},
+		{name: "no name", fm: 143, want: 48925},
+		{name: "unknown name", fm: 143, satName: "LEMUR-2-UNKNOWN", want: 48925},
+		{name: "unknown flight module", fm: 999, satName: "NEW-SAT", want: 55555},
+		{name: "both unknown", fm: 999, satName: "LEMUR-2-UNKNOWN", wantErr: true},
+		{name: "mismatch rejected", fm: 143, satName: "LEMUR-2-ROCKETGIRL", wantErr: true},
+		{name: "mismatch flagged", fm: 143, satName: "LEMUR-2-ROCKETGIRL", mismatch: nameMismatchFlag, want: 48925, flagged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			r := &NoradResolver{flightModules: builtinNorad{}, names: names, mismatch: tt.mismatch}
+			id, note, err := r.NoradID(context.Background(), tt.fm, tt.satName)
+			if tt.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(id, tt.want)
+			is.Equal(note != "", tt.flagged)
+		})
+	}
+}
+
+func TestUDLSatelliteNames(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{tupleBody: `[
+		{"satNo": 48925, "onOrbit": {"satNo": 48925, "commonName": "LEMUR 2 JOHN TREIRES"}},
+		{"satNo": 46502, "onOrbit": {"satNo": 46502, "commonName": "LEMUR-2-ROCKETGIRL", "altName": "FM144"}},
+		{"satNo": 25544}
+	]`}
+	u := udlSatelliteNames(client, time.Hour)
+
+	id, err := u.NoradID(context.Background(), satelliteNameKey("LEMUR-2-JOHN-TREIRES"))
+	is.NoErr(err)
+	is.Equal(id, 48925)
+	id, err = u.NoradID(context.Background(), satelliteNameKey("FM144"))
+	is.NoErr(err)
+	is.Equal(id, 46502)
+	is.Equal(client.tupleQueries, []string{"columns=satNo%2ConOrbit&firstResult=0&maxResults=1000&sort=idElset%2CASC"})
+}
+
+func TestWriteEphemeris_SatelliteNameMismatch(t *testing.T) {
+	path := writeNoradMapping(t, `{"LEMUR-2-JOHN-TREIRES": 11111}`)
+	tests := []struct {
+		name     string
+		mismatch string
+		wantErr  bool
+	}{
+		{name: "reject", wantErr: true},
+		{name: "flag", mismatch: "flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			client := &mockClient{}
+			dest := Destination{client: client}
+			dest.Config.DataType = "EPHEMERIS"
+			dest.Config.EphemerisFormatType = "OEM"
+			dest.Config.SatelliteNameSources = "file"
+			dest.Config.SatelliteNameFile = path
+			dest.Config.SatelliteNameMismatch = tt.mismatch
+			norad, err := newNoradResolver(dest.Config, client)
+			is.NoErr(err)
+			dest.norad = norad
+
+			n, err := dest.Write(context.Background(), []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleFile())}}})
+			if tt.wantErr {
+				is.True(err != nil)
+				is.Equal(n, 0)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(client.ephemIDs, []string{"48925"})
+			is.True(strings.Contains(client.ephemBodies[0], "COMMENT satellite name LEMUR-2-JOHN-TREIRES is NORAD ID 11111, flight module 143 is NORAD ID 48925\n"))
+		})
+	}
+}
+
+func TestWriteEphemeris_SP3SatelliteIDs(t *testing.T) {
+	is := is.New(t)
+	gnss := strings.NewReplacer("143144", "G01E05", "P143", "PG01", "V143", "VG01", "P144", "PE05", "V144", "VE05").Replace(string(sampleFileSP3d()))
+	records := []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(gnss)}}}
+
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	n, err := dest.Write(context.Background(), records)
+	is.Equal(n, 0)
+	is.True(strings.Contains(err.Error(), "no norad mapping for satellite G01")) // GNSS satellites have no flight module
+
+	dest.Config.SP3SatelliteFile = writeNoradMapping(t, `{"G01": 37753, "e05": 40545}`)
+	norad, err := newNoradResolver(dest.Config, client)
+	is.NoErr(err)
+	dest.norad = norad
+	n, err = dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(client.ephemIDs, []string{"37753", "40545"})
+}
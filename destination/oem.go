@@ -0,0 +1,386 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OEM is a CCSDS Orbit Ephemeris Message.
+type OEM struct {
+	Version      string
+	CreationDate string
+	Originator   string
+	Segments     []OEMSegment
+}
+
+// OEMSegment is a metadata block with the ephemeris data it describes.
+type OEMSegment struct {
+	Metadata    OEMMetadata
+	States      []OEMState
+	Covariances []OEMCovariance
+}
+
+type OEMMetadata struct {
+	ObjectName string
+	// ObjectID is usually the international designator, e.g. 1998-067A
+	ObjectID   string
+	CenterName string
+	RefFrame   string
+	TimeSystem string
+	StartTime  string
+	StopTime   string
+}
+
+// OEMState is a state vector with the position in km and velocity in km/s.
+type OEMState struct {
+	Epoch    time.Time
+	Position [3]float64
+	Velocity [3]float64
+}
+
+// OEMCovariance is a position/velocity covariance matrix, Matrix holds the
+// 21 elements of its lower triangle row by row.
+type OEMCovariance struct {
+	Epoch    time.Time
+	RefFrame string
+	Matrix   [21]float64
+}
+
+// ParseOEM parses an OEM in KVN or XML format.
+func ParseOEM(raw []byte) (OEM, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<")) {
+		return parseOEMXML(raw)
+	}
+	return parseOEMKVN(raw)
+}
+
+// oemEpochLayouts are the CCSDS ASCII time code layouts, in calendar and day
+// of year form, with or without a trailing Z.
+var oemEpochLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-002T15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z",
+	"2006-002T15:04:05.999999999Z",
+}
+
+func parseOEMEpoch(v string) (time.Time, error) {
+	for _, layout := range oemEpochLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid OEM epoch %q", v)
+}
+
+func parseOEMKVN(raw []byte) (OEM, error) {
+	var (
+		oem     OEM
+		segment *OEMSegment
+		cov     *OEMCovariance
+		covN    int
+		// block is the block lines belong to: header, meta, data or cov
+		block = "header"
+	)
+	for i, line := range splitLines(raw) {
+		lineNum := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "COMMENT") {
+			continue
+		}
+
+		switch line {
+		case "META_START":
+			oem.Segments = append(oem.Segments, OEMSegment{})
+			segment = &oem.Segments[len(oem.Segments)-1]
+			block = "meta"
+			continue
+		case "META_STOP":
+			block = "data"
+			continue
+		case "COVARIANCE_START":
+			if segment == nil {
+				return OEM{}, fmt.Errorf("line %d: covariance before the first metadata block", lineNum)
+			}
+			block = "cov"
+			continue
+		case "COVARIANCE_STOP":
+			if cov != nil && covN != len(cov.Matrix) {
+				return OEM{}, fmt.Errorf("line %d: covariance has %d of %d elements", lineNum, covN, len(cov.Matrix))
+			}
+			cov = nil
+			block = "data"
+			continue
+		}
+
+		key, value, isKV := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch block {
+		case "header":
+			if !isKV {
+				return OEM{}, fmt.Errorf("line %d: unexpected OEM header line %q", lineNum, line)
+			}
+			switch key {
+			case "CCSDS_OEM_VERS":
+				oem.Version = value
+			case "CREATION_DATE":
+				oem.CreationDate = value
+			case "ORIGINATOR":
+				oem.Originator = value
+			}
+		case "meta":
+			if !isKV {
+				return OEM{}, fmt.Errorf("line %d: unexpected OEM metadata line %q", lineNum, line)
+			}
+			setOEMMetadata(&segment.Metadata, key, value)
+		case "data":
+			if segment == nil {
+				return OEM{}, fmt.Errorf("line %d: state vector before the first metadata block", lineNum)
+			}
+			state, err := parseOEMState(strings.Fields(line))
+			if err != nil {
+				return OEM{}, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			segment.States = append(segment.States, state)
+		case "cov":
+			switch {
+			case isKV && key == "EPOCH":
+				epoch, err := parseOEMEpoch(value)
+				if err != nil {
+					return OEM{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				segment.Covariances = append(segment.Covariances, OEMCovariance{Epoch: epoch})
+				cov = &segment.Covariances[len(segment.Covariances)-1]
+				covN = 0
+			case isKV && key == "COV_REF_FRAME":
+				if cov == nil {
+					return OEM{}, fmt.Errorf("line %d: covariance without epoch", lineNum)
+				}
+				cov.RefFrame = value
+			case !isKV:
+				if cov == nil {
+					return OEM{}, fmt.Errorf("line %d: covariance without epoch", lineNum)
+				}
+				if covN == len(cov.Matrix) {
+					// the next matrix starts with its epoch
+					return OEM{}, fmt.Errorf("line %d: covariance has more than %d elements", lineNum, len(cov.Matrix))
+				}
+				for _, f := range strings.Fields(line) {
+					v, err := strconv.ParseFloat(f, 64)
+					if err != nil {
+						return OEM{}, fmt.Errorf("line %d: %w", lineNum, err)
+					}
+					if covN == len(cov.Matrix) {
+						return OEM{}, fmt.Errorf("line %d: covariance has more than %d elements", lineNum, len(cov.Matrix))
+					}
+					cov.Matrix[covN] = v
+					covN++
+				}
+			}
+		}
+	}
+	return oem, validateOEM(oem)
+}
+
+func setOEMMetadata(md *OEMMetadata, key, value string) {
+	switch key {
+	case "OBJECT_NAME":
+		md.ObjectName = value
+	case "OBJECT_ID":
+		md.ObjectID = value
+	case "CENTER_NAME":
+		md.CenterName = value
+	case "REF_FRAME":
+		md.RefFrame = value
+	case "TIME_SYSTEM":
+		md.TimeSystem = value
+	case "START_TIME":
+		md.StartTime = value
+	case "STOP_TIME":
+		md.StopTime = value
+	}
+}
+
+// parseOEMState parses an epoch followed by the position and velocity, the
+// optional acceleration is ignored.
+func parseOEMState(fields []string) (OEMState, error) {
+	if len(fields) != 7 && len(fields) != 10 {
+		return OEMState{}, fmt.Errorf("state vector has %d fields", len(fields))
+	}
+	epoch, err := parseOEMEpoch(fields[0])
+	if err != nil {
+		return OEMState{}, err
+	}
+	state := OEMState{Epoch: epoch}
+	for k := 0; k < 3; k++ {
+		if state.Position[k], err = strconv.ParseFloat(fields[1+k], 64); err != nil {
+			return OEMState{}, err
+		}
+		if state.Velocity[k], err = strconv.ParseFloat(fields[4+k], 64); err != nil {
+			return OEMState{}, err
+		}
+	}
+	return state, nil
+}
+
+type oemXML struct {
+	Version string `xml:"version,attr"`
+	Header  struct {
+		CreationDate string `xml:"CREATION_DATE"`
+		Originator   string `xml:"ORIGINATOR"`
+	} `xml:"header"`
+	Segments []struct {
+		Metadata struct {
+			ObjectName string `xml:"OBJECT_NAME"`
+			ObjectID   string `xml:"OBJECT_ID"`
+			CenterName string `xml:"CENTER_NAME"`
+			RefFrame   string `xml:"REF_FRAME"`
+			TimeSystem string `xml:"TIME_SYSTEM"`
+			StartTime  string `xml:"START_TIME"`
+			StopTime   string `xml:"STOP_TIME"`
+		} `xml:"metadata"`
+		Data struct {
+			States []struct {
+				Epoch string `xml:"EPOCH"`
+				X     string `xml:"X"`
+				Y     string `xml:"Y"`
+				Z     string `xml:"Z"`
+				XDot  string `xml:"X_DOT"`
+				YDot  string `xml:"Y_DOT"`
+				ZDot  string `xml:"Z_DOT"`
+			} `xml:"stateVector"`
+			Covariances []struct {
+				Epoch    string `xml:"EPOCH"`
+				RefFrame string `xml:"COV_REF_FRAME"`
+				Elements []struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:",any"`
+			} `xml:"covarianceMatrix"`
+		} `xml:"data"`
+	} `xml:"body>segment"`
+}
+
+func parseOEMXML(raw []byte) (OEM, error) {
+	var doc oemXML
+	if err := xml.Unmarshal(raw, &doc); err != nil {
+		return OEM{}, err
+	}
+
+	oem := OEM{
+		Version:      doc.Version,
+		CreationDate: doc.Header.CreationDate,
+		Originator:   doc.Header.Originator,
+	}
+	for _, s := range doc.Segments {
+		segment := OEMSegment{Metadata: OEMMetadata(s.Metadata)}
+		for _, sv := range s.Data.States {
+			state, err := parseOEMState([]string{sv.Epoch, sv.X, sv.Y, sv.Z, sv.XDot, sv.YDot, sv.ZDot})
+			if err != nil {
+				return OEM{}, err
+			}
+			segment.States = append(segment.States, state)
+		}
+		for _, c := range s.Data.Covariances {
+			epoch, err := parseOEMEpoch(strings.TrimSpace(c.Epoch))
+			if err != nil {
+				return OEM{}, err
+			}
+			cov := OEMCovariance{Epoch: epoch, RefFrame: c.RefFrame}
+			n := 0
+			for _, e := range c.Elements {
+				if !strings.HasPrefix(e.XMLName.Local, "C") || e.XMLName.Local == "COMMENT" {
+					continue
+				}
+				if n == len(cov.Matrix) {
+					return OEM{}, fmt.Errorf("covariance at %s has more than %d elements", c.Epoch, len(cov.Matrix))
+				}
+				if cov.Matrix[n], err = strconv.ParseFloat(strings.TrimSpace(e.Value), 64); err != nil {
+					return OEM{}, err
+				}
+				n++
+			}
+			if n != len(cov.Matrix) {
+				return OEM{}, fmt.Errorf("covariance at %s has %d of %d elements", c.Epoch, n, len(cov.Matrix))
+			}
+			segment.Covariances = append(segment.Covariances, cov)
+		}
+		oem.Segments = append(oem.Segments, segment)
+	}
+	return oem, validateOEM(oem)
+}
+
+func validateOEM(oem OEM) error {
+	if oem.Version == "" {
+		return errors.New("invalid input: missing CCSDS_OEM_VERS")
+	}
+	if len(oem.Segments) == 0 {
+		return errors.New("invalid input: OEM contains no segments")
+	}
+	for i, s := range oem.Segments {
+		if s.Metadata.ObjectID == "" {
+			return fmt.Errorf("OEM segment %d has no OBJECT_ID", i+1)
+		}
+		if len(s.States) == 0 {
+			return fmt.Errorf("OEM segment %d has no state vectors", i+1)
+		}
+	}
+	return nil
+}
+
+// OEMToUDL converts every segment of the OEM into a UDL report. The NORAD ID
+// of the object is resolved from its OBJECT_ID and OBJECT_NAME through norad.
+func OEMToUDL(oem OEM, norad *NoradResolver) ([]UDLReport, error) {
+	reports := make([]UDLReport, 0, len(oem.Segments))
+	for _, s := range oem.Segments {
+		noradID, err := norad.ObjectNoradID(context.Background(), s.Metadata.ObjectID, s.Metadata.ObjectName)
+		if errors.Is(err, errNoNoradMapping) {
+			return nil, fmt.Errorf("no norad mapping for object %s", s.Metadata.ObjectID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ur := UDLReport{
+			ID:          strconv.Itoa(noradID),
+			SatelliteID: s.Metadata.ObjectID,
+			ObjectName:  s.Metadata.ObjectName,
+			CenterName:  s.Metadata.CenterName,
+			RefFrame:    s.Metadata.RefFrame,
+			TimeSystem:  s.Metadata.TimeSystem,
+			Covariances: s.Covariances,
+		}
+		for _, st := range s.States {
+			ur.Entries = append(ur.Entries, UDLEntry{
+				Epoch:     st.Epoch,
+				Timestamp: st.Epoch.Format(udlTimeLayout),
+				Position: UDLPosition{
+					X: strconv.FormatFloat(st.Position[0], 'f', 6, 64),
+					Y: strconv.FormatFloat(st.Position[1], 'f', 6, 64),
+					Z: strconv.FormatFloat(st.Position[2], 'f', 6, 64),
+				},
+				Velocity: velocityToUDL(st.Velocity),
+			})
+		}
+		reports = append(reports, ur)
+	}
+	return reports, nil
+}
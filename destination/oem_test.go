@@ -0,0 +1,328 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+)
+
+func sampleOEMKVN() []byte {
+	return []byte(`CCSDS_OEM_VERS = 2.0
+COMMENT  Generated by the flight dynamics system
+CREATION_DATE = 2022-07-06T02:00:00
+ORIGINATOR = SPIRE
+
+META_START
+OBJECT_NAME = LEMUR-2-JOHN-TREIRES
+OBJECT_ID = 48925
+CENTER_NAME = EARTH
+REF_FRAME = EME2000
+TIME_SYSTEM = UTC
+START_TIME = 2022-07-06T01:18:13.000
+STOP_TIME = 2022-07-06T01:18:14.000
+META_STOP
+
+2022-07-06T01:18:13.000 -6658.162753 -1527.302901 -971.376727 0.684482 1.702803 -7.456610
+2022-07-06T01:18:14.000 -6657.474119 -1525.599224 -978.832746 0.692784 1.704549 -7.455422
+
+COVARIANCE_START
+EPOCH = 2022-07-06T01:18:13.000
+COV_REF_FRAME = RTN
+3.3313494e-04
+4.6189273e-04 6.7824216e-04
+-3.0700078e-04 -4.2212341e-04 3.2319319e-04
+-3.3493650e-07 -4.6860842e-07 2.4849495e-07 4.2960228e-10
+-2.2118325e-07 -2.8641868e-07 1.7980986e-07 2.6088992e-10 1.7675147e-10
+-3.0413460e-07 -4.9894969e-07 3.5403109e-07 1.8692631e-10 1.0088625e-10 6.2244443e-10
+COVARIANCE_STOP
+
+META_START
+OBJECT_NAME = LEMUR-2-ROCKETGIRL
+OBJECT_ID = 46502
+CENTER_NAME = EARTH
+REF_FRAME = EME2000
+TIME_SYSTEM = UTC
+START_TIME = 2022-187T01:18:13
+STOP_TIME = 2022-187T01:18:13
+META_STOP
+
+2022-187T01:18:13 5123.456789 3210.987654 -2468.135790 -0.452112 5.231065 1.123457
+`)
+}
+
+func sampleOEMXML() []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<oem id="CCSDS_OEM_VERS" version="2.0">
+  <header>
+    <CREATION_DATE>2022-07-06T02:00:00</CREATION_DATE>
+    <ORIGINATOR>SPIRE</ORIGINATOR>
+  </header>
+  <body>
+    <segment>
+      <metadata>
+        <OBJECT_NAME>LEMUR-2-JOHN-TREIRES</OBJECT_NAME>
+        <OBJECT_ID>48925</OBJECT_ID>
+        <CENTER_NAME>EARTH</CENTER_NAME>
+        <REF_FRAME>EME2000</REF_FRAME>
+        <TIME_SYSTEM>UTC</TIME_SYSTEM>
+        <START_TIME>2022-07-06T01:18:13.000</START_TIME>
+        <STOP_TIME>2022-07-06T01:18:14.000</STOP_TIME>
+      </metadata>
+      <data>
+        <stateVector>
+          <EPOCH>2022-07-06T01:18:13.000</EPOCH>
+          <X>-6658.162753</X><Y>-1527.302901</Y><Z>-971.376727</Z>
+          <X_DOT>0.684482</X_DOT><Y_DOT>1.702803</Y_DOT><Z_DOT>-7.456610</Z_DOT>
+        </stateVector>
+        <stateVector>
+          <EPOCH>2022-07-06T01:18:14.000</EPOCH>
+          <X>-6657.474119</X><Y>-1525.599224</Y><Z>-978.832746</Z>
+          <X_DOT>0.692784</X_DOT><Y_DOT>1.704549</Y_DOT><Z_DOT>-7.455422</Z_DOT>
+        </stateVector>
+        <covarianceMatrix>
+          <EPOCH>2022-07-06T01:18:13.000</EPOCH>
+          <COV_REF_FRAME>RTN</COV_REF_FRAME>
+          <CX_X>3.3313494e-04</CX_X>
+          <CY_X>4.6189273e-04</CY_X><CY_Y>6.7824216e-04</CY_Y>
+          <CZ_X>-3.0700078e-04</CZ_X><CZ_Y>-4.2212341e-04</CZ_Y><CZ_Z>3.2319319e-04</CZ_Z>
+          <CX_DOT_X>-3.3493650e-07</CX_DOT_X><CX_DOT_Y>-4.6860842e-07</CX_DOT_Y><CX_DOT_Z>2.4849495e-07</CX_DOT_Z><CX_DOT_X_DOT>4.2960228e-10</CX_DOT_X_DOT>
+          <CY_DOT_X>-2.2118325e-07</CY_DOT_X><CY_DOT_Y>-2.8641868e-07</CY_DOT_Y><CY_DOT_Z>1.7980986e-07</CY_DOT_Z><CY_DOT_X_DOT>2.6088992e-10</CY_DOT_X_DOT><CY_DOT_Y_DOT>1.7675147e-10</CY_DOT_Y_DOT>
+          <CZ_DOT_X>-3.0413460e-07</CZ_DOT_X><CZ_DOT_Y>-4.9894969e-07</CZ_DOT_Y><CZ_DOT_Z>3.5403109e-07</CZ_DOT_Z><CZ_DOT_X_DOT>1.8692631e-10</CZ_DOT_X_DOT><CZ_DOT_Y_DOT>1.0088625e-10</CZ_DOT_Y_DOT><CZ_DOT_Z_DOT>6.2244443e-10</CZ_DOT_Z_DOT>
+        </covarianceMatrix>
+      </data>
+    </segment>
+  </body>
+</oem>
+`)
+}
+
+func TestParseOEM_KVN(t *testing.T) {
+	is := is.New(t)
+
+	oem, err := ParseOEM(sampleOEMKVN())
+	is.NoErr(err)
+	is.Equal(oem.Version, "2.0")
+	is.Equal(oem.Originator, "SPIRE")
+	is.Equal(len(oem.Segments), 2)
+
+	s := oem.Segments[0]
+	is.Equal(s.Metadata, OEMMetadata{
+		ObjectName: "LEMUR-2-JOHN-TREIRES",
+		ObjectID:   "48925",
+		CenterName: "EARTH",
+		RefFrame:   "EME2000",
+		TimeSystem: "UTC",
+		StartTime:  "2022-07-06T01:18:13.000",
+		StopTime:   "2022-07-06T01:18:14.000",
+	})
+	is.Equal(len(s.States), 2)
+	is.Equal(s.States[1].Epoch, time.Date(2022, 7, 6, 1, 18, 14, 0, time.UTC))
+	is.Equal(s.States[1].Position, [3]float64{-6657.474119, -1525.599224, -978.832746})
+	is.Equal(s.States[1].Velocity, [3]float64{0.692784, 1.704549, -7.455422})
+	is.Equal(len(s.Covariances), 1)
+	is.Equal(s.Covariances[0].RefFrame, "RTN")
+	is.Equal(s.Covariances[0].Matrix[0], 3.3313494e-04)
+	is.Equal(s.Covariances[0].Matrix[20], 6.2244443e-10)
+
+	// day of year epochs
+	is.Equal(oem.Segments[1].States[0].Epoch, time.Date(2022, 7, 6, 1, 18, 13, 0, time.UTC))
+}
+
+func TestParseOEM_XML(t *testing.T) {
+	is := is.New(t)
+
+	xmlOEM, err := ParseOEM(sampleOEMXML())
+	is.NoErr(err)
+	kvnOEM, err := ParseOEM(sampleOEMKVN())
+	is.NoErr(err)
+
+	is.Equal(xmlOEM.Version, "2.0")
+	is.Equal(len(xmlOEM.Segments), 1)
+	is.Equal(xmlOEM.Segments[0], kvnOEM.Segments[0]) // both encodings parse to the same segment
+}
+
+func TestParseOEM_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{
+			name: "incomplete covariance",
+			raw: `CCSDS_OEM_VERS = 2.0
+META_START
+OBJECT_ID = 48925
+META_STOP
+2022-07-06T01:18:13.000 -6658.162753 -1527.302901 -971.376727 0.684482 1.702803 -7.456610
+COVARIANCE_START
+EPOCH = 2022-07-06T01:18:13.000
+3.3313494e-04
+COVARIANCE_STOP
+`,
+		},
+		{
+			name: "state vector without metadata",
+			raw:  "CCSDS_OEM_VERS = 2.0\nMETA_STOP\n2020-01-01T00:00:00 1 2 3 4 5 6\n",
+		},
+		{
+			name: "covariance without metadata",
+			raw:  "CCSDS_OEM_VERS = 2.0\nCOVARIANCE_START\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := ParseOEM([]byte(tt.raw))
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestOEMToUDL(t *testing.T) {
+	is := is.New(t)
+
+	reports, err := ToUDLEphemerisOEM(sampleOEMKVN(), defaultNoradResolver)
+	is.NoErr(err)
+	is.Equal(len(reports), 2)
+	is.Equal(reports[0].ID, "48925")
+	is.Equal(reports[1].ID, "46502")
+	is.Equal(reports[0].RefFrame, "EME2000")
+	is.Equal(reports[0].ObjectName, "LEMUR-2-JOHN-TREIRES")
+	is.Equal(reports[0].Entries[0], UDLEntry{
+		Epoch:     time.Date(2022, 7, 6, 1, 18, 13, 0, time.UTC),
+		Timestamp: "22187011813.000",
+		Position:  UDLPosition{X: "-6658.162753", Y: "-1527.302901", Z: "-971.376727"},
+		Velocity:  UDLVelocity{X: "0.6844820000", Y: "1.7028030000", Z: "-7.4566100000"},
+	})
+	is.Equal(len(reports[0].Covariances), 1)
+	is.Equal(reports[0].Covariances[0].RefFrame, "RTN")
+}
+
+func TestOEMToUDL_ObjectID(t *testing.T) {
+	names := &fileCatalog[string]{
+		path: writeNoradMapping(t, `{"2021-059A": 48925, "LEMUR-2-ROCKETGIRL": 46502}`),
+		key: func(s string) (string, bool) {
+			return satelliteNameKey(s), true
+		},
+	}
+	tests := []struct {
+		name       string
+		objectID   string
+		objectName string
+		names      catalog[string]
+		want       int
+		wantErr    bool
+	}{
+		{name: "norad id", objectID: "25544", want: 25544},
+		{name: "designator", objectID: "2021-059A", names: names, want: 48925},
+		{name: "object name", objectID: "2020-068B", objectName: "Lemur 2 Rocketgirl", names: names, want: 46502},
+		{name: "unknown designator", objectID: "1998-067A", objectName: "ISS (ZARYA)", names: names, wantErr: true},
+		{name: "designator without name sources", objectID: "2021-059A", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			oem, err := ParseOEM(sampleOEMKVN())
+			is.NoErr(err)
+			oem.Segments = oem.Segments[:1]
+			oem.Segments[0].Metadata.ObjectID = tt.objectID
+			oem.Segments[0].Metadata.ObjectName = tt.objectName
+
+			r := &NoradResolver{flightModules: builtinNorad{}}
+			if tt.names != nil {
+				r.names = tt.names
+			}
+			reports, err := OEMToUDL(oem, r)
+			if tt.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(reports[0].ID, strconv.Itoa(tt.want))
+			is.Equal(reports[0].SatelliteID, tt.objectID)
+		})
+	}
+}
+
+func TestDetectEphemerisFormat(t *testing.T) {
+	is := is.New(t)
+
+	for raw, want := range map[string]string{
+		string(sampleFile()):     ephemerisFormatSP3,
+		string(sampleOEMKVN()):   ephemerisFormatOEM,
+		string(sampleOEMXML()):   ephemerisFormatOEM,
+		"\n  CCSDS_OEM_VERS = 3": ephemerisFormatOEM,
+	} {
+		got, err := detectEphemerisFormat([]byte(raw))
+		is.NoErr(err)
+		is.Equal(got, want)
+	}
+	_, err := detectEphemerisFormat([]byte(`{"id": 1}`))
+	is.True(err != nil)
+}
+
+func TestWriteEphemeris_OEM(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(sampleOEMXML())}},
+		{Payload: sdk.Change{After: sdk.RawData(sampleFile())}},
+		{Payload: sdk.Change{After: sdk.RawData(sampleOEMKVN())}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 3)
+	is.Equal(client.ephemIDs, []string{"48925", "48925", "48925", "46502"})
+}
+
+func TestWriteEphemeris_ConfiguredInputFormat(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.EphemerisInputFormat = "sp3"
+
+	n, err := dest.Write(context.Background(), []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleOEMKVN())}}})
+	is.True(err != nil) // an OEM is not parsed as SP3
+	is.Equal(n, 0)
+}
+
+func TestWriteEphemeris_OEMCovariance(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.EphemerisFormatType = "OEM"
+
+	n, err := dest.Write(context.Background(), []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleOEMKVN())}}})
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.True(strings.Contains(client.ephemBodies[0], "\nCOVARIANCE_START\nEPOCH = 2022-07-06T01:18:13.000000\nCOV_REF_FRAME = RTN\n3.3313494e-04\n4.6189273e-04 6.7824216e-04\n"))
+	is.True(strings.HasSuffix(client.ephemBodies[0], " 6.2244443e-10\nCOVARIANCE_STOP\n"))
+	is.True(!strings.Contains(client.ephemBodies[1], "COVARIANCE")) // the second segment has no covariance
+
+	submitted, err := ParseOEM([]byte(client.ephemBodies[0]))
+	is.NoErr(err)
+	is.Equal(submitted.Segments[0].Covariances[0].Matrix[20], 6.2244443e-10)
+}
@@ -9,6 +9,14 @@ import (
 
 func (Config) Parameters() map[string]sdk.Parameter {
 	return map[string]sdk.Parameter{
+		"aisInputFormat": {
+			Default:     "auto",
+			Description: "The format of AIS records. Acceptable values are spire (Spire vessel JSON), nmea (NMEA 0183 AIVDM/AIVDO sentences, one per line) and auto, which detects the format of every record.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"auto", "spire", "nmea"}},
+			},
+		},
 		"baseURL": {
 			Default:     "https://unifieddatalibrary.com",
 			Description: "The Base URL to use to access the UDL. The default is https://unifieddatalibrary.com.",
@@ -31,12 +39,70 @@ func (Config) Parameters() map[string]sdk.Parameter {
 		},
 		"dataType": {
 			Default:     "AIS",
-			Description: "The Data Type that is being submitted to the UDL. Acceptable values are AIS and ELSET.",
+			Description: "The Data Type that is being submitted to the UDL. Acceptable values are AIS, ELSET, EPHEMERIS and TLE.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"AIS", "ELSET", "EPHEMERIS", "TLE"}},
+			},
+		},
+		"elsetFillMissing": {
+			Default:     "false",
+			Description: "Whether dataMode, classificationMarking and elsetSource only fill in the values missing from ELSET records. By default they replace the values of the records.",
+			Type:        sdk.ParameterTypeBool,
+			Validations: []sdk.Validation{},
+		},
+		"elsetSource": {
+			Default:     "Spire",
+			Description: "The source of submitted elsets.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"ephemerisCategory": {
+			Default:     "EXTERNAL",
+			Description: "The source category of submitted ephemeris, e.g. OWNER_OPERATOR, ANALYST or EXTERNAL.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"ephemerisFormatType": {
+			Default:     "NASA",
+			Description: "The format of submitted ephemeris as documented in the Flight Safety Handbook. Acceptable values are GOO, ModITC, NASA, OASYS and OEM. With OEM the ephemeris are submitted as CCSDS OEM, otherwise in the text format.",
 			Type:        sdk.ParameterTypeString,
 			Validations: []sdk.Validation{
-				sdk.ValidationInclusion{List: []string{"AIS", "ELSET", "EPHEMERIS"}},
+				sdk.ValidationInclusion{List: []string{"GOO", "ModITC", "NASA", "OASYS", "OEM"}},
 			},
 		},
+		"ephemerisHasManeuver": {
+			Default:     "false",
+			Description: "Whether maneuvers are incorporated into submitted ephemeris.",
+			Type:        sdk.ParameterTypeBool,
+			Validations: []sdk.Validation{},
+		},
+		"ephemerisInputFormat": {
+			Default:     "auto",
+			Description: "The format of ephemeris records. Acceptable values are sp3, oem (CCSDS OEM in KVN or XML) and auto, which detects the format of every record.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"auto", "sp3", "oem"}},
+			},
+		},
+		"ephemerisOrigin": {
+			Default:     "",
+			Description: "The originating system or organization of submitted ephemeris, if different from the source.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"ephemerisSource": {
+			Default:     "Spire",
+			Description: "The source of submitted ephemeris.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"ephemerisType": {
+			Default:     "ROUTINE",
+			Description: "The type/purpose of submitted ephemeris, e.g. LAUNCH, ROUTINE, MNVR_PLAN or SCREENING.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
 		"httpBasicAuthPassword": {
 			Default:     "",
 			Description: "The HTTP Basic Auth Password to use when accessing the UDL.",
@@ -53,5 +119,151 @@ func (Config) Parameters() map[string]sdk.Parameter {
 				sdk.ValidationRequired{},
 			},
 		},
+		"maxBytesPerRequest": {
+			Default:     "0",
+			Description: "The maximum size in bytes of the body of a single AIS or elset filedrop or TLE request. Larger batches are split into several requests. 0 disables the limit.",
+			Type:        sdk.ParameterTypeInt,
+			Validations: []sdk.Validation{},
+		},
+		"maxRecordsPerRequest": {
+			Default:     "0",
+			Description: "The maximum number of AIS records, elsets or TLEs sent to the UDL in a single request. Larger batches are split into several requests. 0 disables the limit.",
+			Type:        sdk.ParameterTypeInt,
+			Validations: []sdk.Validation{},
+		},
+		"nmeaSource": {
+			Default:     "Spire",
+			Description: "The source of AIS records decoded from NMEA sentences, usually the provider of the AIS feed. Spire vessel JSON is always submitted with source Spire.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"noradCacheTTL": {
+			Default:     "1h",
+			Description: "How long NORAD IDs looked up in the UDL are cached.",
+			Type:        sdk.ParameterTypeDuration,
+			Validations: []sdk.Validation{},
+		},
+		"noradMappingFile": {
+			Default:     "",
+			Description: "Path of a JSON file mapping flight module numbers to NORAD IDs, e.g. {\"144\": 46502}. The file is read again when it changes.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"noradSources": {
+			Default:     "builtin",
+			Description: "Comma-separated list of the sources the NORAD IDs of Spire flight modules are resolved through, in order. Acceptable sources are builtin (the table compiled into the connector), file (noradMappingFile) and udl (the current elsets of elsetSource in the UDL).",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"rateLimit": {
+			Default:     "0",
+			Description: "The maximum number of requests per second sent to the UDL, shared by all endpoints. 0 disables the limit.",
+			Type:        sdk.ParameterTypeFloat,
+			Validations: []sdk.Validation{},
+		},
+		"rateLimitBurst": {
+			Default:     "1",
+			Description: "The maximum number of requests sent to the UDL in a single burst when rateLimit is set.",
+			Type:        sdk.ParameterTypeInt,
+			Validations: []sdk.Validation{},
+		},
+		"retryMaxAttempts": {
+			Default:     "5",
+			Description: "The maximum number of times a request is sent to the UDL when it fails with 429, a 5xx status or a network error. 4xx errors are never retried.",
+			Type:        sdk.ParameterTypeInt,
+			Validations: []sdk.Validation{},
+		},
+		"retryTimeout": {
+			Default:     "2m",
+			Description: "The maximum total time spent on a request to the UDL, including all attempts and the waits between them.",
+			Type:        sdk.ParameterTypeDuration,
+			Validations: []sdk.Validation{},
+		},
+		"satelliteNameFile": {
+			Default:     "",
+			Description: "Path of a JSON file mapping satellite names to NORAD IDs, e.g. {\"LEMUR-2-JOHN-TREIRES\": 46502}. The file is read again when it changes.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"satelliteNameMismatch": {
+			Default:     "reject",
+			Description: "What to do with a satellite whose name and flight module resolve to different NORAD IDs. Acceptable values are reject and flag, which submits the ephemeris under the NORAD ID of the flight module and logs the mismatch.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"reject", "flag"}},
+			},
+		},
+		"satelliteNameSources": {
+			Default:     "",
+			Description: "Comma-separated list of the sources the NORAD IDs of SP3 satellite names and OEM objects are resolved through, in order. Acceptable sources are file (satelliteNameFile) and udl (the on-orbit objects of the current elsets in the UDL). By default satellite names are not resolved.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"shipTypeMappingFile": {
+			Default:     "",
+			Description: "Path of a JSON file with ship type mappings that are layered on top of the built-in mappings of Spire ship types.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"sp3SatelliteFile": {
+			Default:     "",
+			Description: "Path of a JSON file mapping SP3 satellite IDs to NORAD IDs, e.g. {\"G01\": 37753}. Satellites that are not Spire flight modules, such as those of GNSS precise orbit products, are resolved through it. The file is read again when it changes.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{},
+		},
+		"spireSchemaVersion": {
+			Default:     "auto",
+			Description: "The schema version of Spire vessel JSON. 1 is the Spire Vessels REST API, 2 is Spire Maritime 2.0 (GraphQL) and auto detects the version of every record.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"auto", "1", "2"}},
+			},
+		},
+		"tleMakeCurrent": {
+			Default:     "false",
+			Description: "Whether elsets created from TLEs are set as the current elset of their satellite.",
+			Type:        sdk.ParameterTypeBool,
+			Validations: []sdk.Validation{},
+		},
+		"tleSubmitMethod": {
+			Default:     "bulk",
+			Description: "How TLEs are submitted. bulk posts the TLE text to createBulkFromTLE, filedrop parses the TLEs into elsets and posts them to the elset filedrop.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"bulk", "filedrop"}},
+			},
+		},
+		"transformErrorPolicy": {
+			Default:     "fail",
+			Description: "What to do with a record that can not be transformed into the UDL model. fail stops the pipeline, skip logs and drops the record, dlq nacks the record so the pipeline's dead-letter queue receives it.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"fail", "skip", "dlq"}},
+			},
+		},
+		"unmappedShipType": {
+			Default:     "other",
+			Description: "What to do with a ship type that has no mapping. Acceptable values are fail, passthrough (the ship type is submitted as is) and other (the ship type is submitted as Other).",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"fail", "passthrough", "other"}},
+			},
+		},
+		"velocityMethod": {
+			Default:     "lagrange",
+			Description: "How velocities are derived for SP3 files without velocity records. lagrange differentiates a Lagrange interpolating polynomial through velocityPoints positions, difference uses central differences, none rejects such files.",
+			Type:        sdk.ParameterTypeString,
+			Validations: []sdk.Validation{
+				sdk.ValidationInclusion{List: []string{"lagrange", "difference", "none"}},
+			},
+		},
+		"velocityPoints": {
+			Default:     "9",
+			Description: "The number of positions the Lagrange interpolating polynomial is fitted through when velocityMethod is lagrange.",
+			Type:        sdk.ParameterTypeInt,
+			Validations: []sdk.Validation{
+				sdk.ValidationGreaterThan{Value: 1},
+			},
+		},
 	}
 }
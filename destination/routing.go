@@ -0,0 +1,73 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// MetadataDataType is the record metadata key that overrides the configured
+// data type for a single record.
+const MetadataDataType = "udl.dataType"
+
+// recordGroup holds a run of consecutive records of one data type together
+// with their index in the batch passed to Write.
+type recordGroup struct {
+	dataType string
+	records  []sdk.Record
+	indices  []int
+}
+
+// groupByDataType splits records into runs of consecutive records of the same
+// data type, taken from their metadata with a fallback to the configured data
+// type. Runs are returned in record order, so a failed run can stop the
+// records after it from being submitted.
+func (d *Destination) groupByDataType(records []sdk.Record) ([]*recordGroup, error) {
+	var groups []*recordGroup
+	for i, r := range records {
+		dataType := d.Config.DataType
+		if v, ok := r.Metadata[MetadataDataType]; ok && v != "" {
+			dataType = v
+		}
+		dataType = canonicalDataType(dataType)
+		if _, ok := writers[dataType]; !ok {
+			return nil, fmt.Errorf("record %d: unsupported data type: %s", i, dataType)
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].dataType != dataType {
+			groups = append(groups, &recordGroup{dataType: dataType})
+		}
+		g := groups[len(groups)-1]
+		g.records = append(g.records, r)
+		g.indices = append(g.indices, i)
+	}
+	return groups, nil
+}
+
+// firstError returns the number of leading records that were written and the
+// error of the first record that was not. Only the default single record
+// writes of the SDK ack and redeliver records by this count: with sdk.batch.size
+// or sdk.batch.delay set, SDK v0.7.2 nacks the first n records with the error
+// of the batch and never acks the others.
+func firstError(errs []error) (int, error) {
+	for i, err := range errs {
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(errs), nil
+}
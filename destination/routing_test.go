@@ -0,0 +1,125 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+)
+
+func aisRecord() sdk.Record {
+	return sdk.Record{
+		Metadata: sdk.Metadata{},
+		Payload:  sdk.Change{After: sdk.RawData(`{"id": "1", "updateTimestamp": "2022-01-01T00:00:00.000Z"}`)},
+	}
+}
+
+func elsetRecord() sdk.Record {
+	return sdk.Record{
+		Metadata: sdk.Metadata{MetadataDataType: "ELSET"},
+		Payload:  sdk.Change{After: sdk.RawData(`{"idOnOrbit": "1", "epoch": "2022-01-01T00:00:00.000Z"}`)},
+	}
+}
+
+func TestGroupByDataType(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	dest.Config.DataType = "AIS"
+
+	records := []sdk.Record{elsetRecord(), aisRecord(), elsetRecord()}
+	records[2].Metadata[MetadataDataType] = "elset"
+
+	records = append(records, elsetRecord())
+
+	groups, err := dest.groupByDataType(records)
+	is.NoErr(err)
+	is.Equal(len(groups), 3)
+	is.Equal(groups[0].dataType, "ELSET") // groups are runs in record order
+	is.Equal(groups[0].indices, []int{0})
+	is.Equal(groups[1].dataType, "AIS") // records without metadata use the configured type
+	is.Equal(groups[1].indices, []int{1})
+	is.Equal(groups[2].dataType, "ELSET")
+	is.Equal(groups[2].indices, []int{2, 3})
+}
+
+func TestGroupByDataType_Unsupported(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	dest.Config.DataType = "AIS"
+
+	records := []sdk.Record{aisRecord(), aisRecord()}
+	records[1].Metadata[MetadataDataType] = "AIRCRAFT"
+
+	_, err := dest.groupByDataType(records)
+	is.True(err != nil)
+}
+
+func TestWrite_Routing(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	dest.Config.DataMode = "TEST"
+
+	n, err := dest.Write(context.Background(), []sdk.Record{aisRecord(), elsetRecord(), aisRecord()})
+	is.NoErr(err)
+	is.Equal(n, 3)
+	is.Equal(len(client.ais), 2)
+	is.Equal(len(client.elsets), 1)
+}
+
+func TestWrite_RoutingPartialFailure(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{mockElset: {status: http.StatusBadRequest}}}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	dest.Config.DataMode = "TEST"
+
+	n, err := dest.Write(context.Background(), []sdk.Record{aisRecord(), elsetRecord(), aisRecord()})
+	is.True(err != nil)
+	is.Equal(n, 1)               // only the records before the failed elset are acknowledged
+	is.Equal(len(client.ais), 1) // nothing after the failed elset is submitted
+}
+
+func TestFirstError(t *testing.T) {
+	is := is.New(t)
+	errA, errB := errors.New("a"), errors.New("b")
+
+	n, err := firstError([]error{nil, nil, errA, nil, errB})
+	is.Equal(n, 2)
+	is.Equal(err, errA)
+	n, err = firstError([]error{errB, nil})
+	is.Equal(n, 0)
+	is.Equal(err, errB)
+	n, err = firstError([]error{nil, nil})
+	is.Equal(n, 2)
+	is.NoErr(err)
+	n, err = firstError(nil)
+	is.Equal(n, 0)
+	is.NoErr(err)
+}
+
+func TestBatchedWrites(t *testing.T) {
+	is := is.New(t)
+	is.True(!batchedWrites(map[string]string{}))
+	is.True(!batchedWrites(map[string]string{"sdk.batch.size": "0"}))
+	is.True(batchedWrites(map[string]string{"sdk.batch.size": "1"}))
+	is.True(batchedWrites(map[string]string{"sdk.batch.delay": "1s"}))
+}
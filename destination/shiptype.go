@@ -0,0 +1,161 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// Policies for Spire ship types without a mapping.
+const (
+	unmappedShipTypeFail        = "fail"
+	unmappedShipTypePassthrough = "passthrough"
+	unmappedShipTypeOther       = "other"
+)
+
+// ShipTypeMapping maps Spire ship types into the USCG NAVCEN ship types of
+// the UDL and the engagedIn, specialCraft and cargoType entries they imply.
+// Ship types are matched case-insensitively, with underscores read as spaces.
+type ShipTypeMapping struct {
+	// ShipTypes maps Spire ship types into NAVCEN ship types.
+	ShipTypes map[string]string `json:"shipTypes"`
+	// CargoTypes maps Spire ship types into the cargo type of the vessel.
+	CargoTypes map[string]string `json:"cargoTypes"`
+	// EngagedIn lists the Spire ship types whose sub type is what the vessel
+	// is engaged in.
+	EngagedIn []string `json:"engagedIn"`
+	// SpecialCraft lists the Spire ship types that are special craft.
+	SpecialCraft []string `json:"specialCraft"`
+
+	unmapped string
+}
+
+// defaultShipTypes holds the built-in mappings, unmapped ship types are
+// submitted as Other.
+var defaultShipTypes = &ShipTypeMapping{
+	ShipTypes:    spireToNavcenShipTypeMapping,
+	CargoTypes:   cargoTypeMapping,
+	EngagedIn:    engagedIn,
+	SpecialCraft: specialCraft,
+	unmapped:     unmappedShipTypeOther,
+}
+
+// LoadShipTypeMapping returns the built-in mappings with the mappings of the
+// JSON file at path layered on top of them. Without a path only the built-in
+// mappings are used.
+func LoadShipTypeMapping(path, unmapped string) (*ShipTypeMapping, error) {
+	unmapped = strings.ToLower(strings.TrimSpace(unmapped))
+	switch unmapped {
+	case "":
+		unmapped = unmappedShipTypeOther
+	case unmappedShipTypeFail, unmappedShipTypePassthrough, unmappedShipTypeOther:
+	default:
+		return nil, fmt.Errorf("unsupported unmapped ship type policy: %s", unmapped)
+	}
+
+	m := &ShipTypeMapping{
+		ShipTypes:    make(map[string]string),
+		CargoTypes:   make(map[string]string),
+		EngagedIn:    slices.Clone(defaultShipTypes.EngagedIn),
+		SpecialCraft: slices.Clone(defaultShipTypes.SpecialCraft),
+		unmapped:     unmapped,
+	}
+	for k, v := range defaultShipTypes.ShipTypes {
+		m.ShipTypes[k] = v
+	}
+	for k, v := range defaultShipTypes.CargoTypes {
+		m.CargoTypes[k] = v
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ship type mapping: %w", err)
+	}
+	var file ShipTypeMapping
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("ship type mapping %s: %w", path, err)
+	}
+	for k, v := range file.ShipTypes {
+		m.ShipTypes[shipTypeKey(k)] = v
+	}
+	for k, v := range file.CargoTypes {
+		m.CargoTypes[shipTypeKey(k)] = v
+	}
+	for _, t := range file.EngagedIn {
+		if t = shipTypeKey(t); !slices.Contains(m.EngagedIn, t) {
+			m.EngagedIn = append(m.EngagedIn, t)
+		}
+	}
+	for _, t := range file.SpecialCraft {
+		if t = shipTypeKey(t); !slices.Contains(m.SpecialCraft, t) {
+			m.SpecialCraft = append(m.SpecialCraft, t)
+		}
+	}
+	return m, nil
+}
+
+// shipTypeKey returns the Spire ship type in the form of the mapping keys.
+func shipTypeKey(shipType string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(shipType), "_", " "))
+}
+
+// setAISShipType sets the NAVCEN ship type of a Spire ship type and its
+// engagedIn, specialCraft or cargoType entry.
+func (m *ShipTypeMapping) setAISShipType(shipType, shipSubType string, ais *udl.AISIngest) error {
+	key := shipTypeKey(shipType)
+	navCenShipType, ok := m.ShipTypes[key]
+	if !ok {
+		switch m.unmapped {
+		case unmappedShipTypeFail:
+			return fmt.Errorf("no ship type mapping for %s", shipType)
+		case unmappedShipTypePassthrough:
+			navCenShipType = shipType
+		default:
+			navCenShipType = "Other"
+		}
+	}
+	ais.ShipType = &navCenShipType
+
+	if slices.Contains(m.EngagedIn, key) && shipSubType != "" {
+		ais.EngagedIn = &shipSubType
+	}
+	if slices.Contains(m.SpecialCraft, key) {
+		specialCraftValue := toTitleCase(key)
+		ais.SpecialCraft = &specialCraftValue
+	}
+	if cargoTypeValue, ok := m.CargoTypes[key]; ok {
+		ais.CargoType = &cargoTypeValue
+	}
+	return nil
+}
+
+// checkShipTypes validates the configured ship type mapping file and
+// unmapped ship type policy.
+func checkShipTypes(cfg Config) error {
+	_, err := LoadShipTypeMapping(cfg.ShipTypeMappingFile, cfg.UnmappedShipType)
+	return err
+}
@@ -0,0 +1,149 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// writeShipTypeMapping writes a ship type mapping file and returns its path.
+func writeShipTypeMapping(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "shiptypes.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestShipTypeMapping_SetAISShipType(t *testing.T) {
+	path := writeShipTypeMapping(t, `{
+		"shipTypes": {"wing_in_ground": "Other", "DRY BULK": "Bulk Carrier"},
+		"cargoTypes": {"WING IN GROUND": "Hovercraft"},
+		"engagedIn": ["wing_in_ground"],
+		"specialCraft": ["ICE BREAKER"]
+	}`)
+
+	tests := []struct {
+		name         string
+		path         string
+		unmapped     string
+		shipType     string
+		subType      string
+		want         string
+		cargoType    string
+		engagedIn    string
+		specialCraft string
+		wantErr      bool
+	}{
+		{name: "built-in", shipType: "GENERAL CARGO", want: "Cargo", cargoType: "General Cargo"},
+		{name: "built-in engaged in", shipType: "DREDGER", subType: "DREDGING", want: "Other", engagedIn: "DREDGING"},
+		{name: "built-in special craft", shipType: "PILOT VESSEL", want: "Special Craft", specialCraft: "Pilot Vessel"},
+		{name: "unmapped other", shipType: "WING IN GROUND", want: "Other"},
+		{name: "unmapped passthrough", unmapped: "passthrough", shipType: "WING IN GROUND", want: "WING IN GROUND"},
+		{name: "unmapped fail", unmapped: "fail", shipType: "WING IN GROUND", wantErr: true},
+		{name: "file", path: path, unmapped: "fail", shipType: "WING IN GROUND", subType: "RACING", want: "Other", cargoType: "Hovercraft", engagedIn: "RACING"},
+		{name: "file overrides built-in", path: path, shipType: "DRY BULK", want: "Bulk Carrier", cargoType: "Dry Bulk"},
+		{name: "file keeps built-in", path: path, shipType: "TUG", want: "Tug"},
+		{name: "file special craft", path: path, shipType: "ICE_BREAKER", want: "Other", specialCraft: "Ice Breaker"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			m, err := LoadShipTypeMapping(tt.path, tt.unmapped)
+			is.NoErr(err)
+
+			var ais udl.AISIngest
+			err = m.setAISShipType(tt.shipType, tt.subType, &ais)
+			if tt.wantErr {
+				is.True(err != nil)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(*ais.ShipType, tt.want)
+			value := func(s *string) string {
+				if s == nil {
+					return ""
+				}
+				return *s
+			}
+			is.Equal(value(ais.CargoType), tt.cargoType)
+			is.Equal(value(ais.EngagedIn), tt.engagedIn)
+			is.Equal(value(ais.SpecialCraft), tt.specialCraft)
+		})
+	}
+}
+
+func TestLoadShipTypeMapping_DoesNotChangeBuiltIns(t *testing.T) {
+	is := is.New(t)
+	path := writeShipTypeMapping(t, `{"shipTypes": {"TUG": "Towing"}, "engagedIn": ["TUG"]}`)
+
+	_, err := LoadShipTypeMapping(path, "")
+	is.NoErr(err)
+	is.Equal(spireToNavcenShipTypeMapping["TUG"], "Tug")
+	is.Equal(len(defaultShipTypes.EngagedIn), len(engagedIn))
+}
+
+func TestLoadShipTypeMapping_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		unmapped string
+	}{
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.json")},
+		{name: "invalid json", path: writeShipTypeMapping(t, `{"shipTypes": [`)},
+		{name: "unknown key", path: writeShipTypeMapping(t, `{"shipType": {"TUG": "Tug"}}`)},
+		{name: "unsupported policy", unmapped: "drop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := LoadShipTypeMapping(tt.path, tt.unmapped)
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestToUDLAisSpire_UnmappedShipType(t *testing.T) {
+	is := is.New(t)
+	raw := []byte(`{"updateTimestamp": "2023-02-23T13:09:04.374Z", "staticData": {"shipType": "WING_IN_GROUND"}}`)
+
+	m, err := LoadShipTypeMapping("", "fail")
+	is.NoErr(err)
+	_, err = ToUDLAisSpire(raw, "auto", m, udl.AISIngestDataModeTEST, "U")
+	is.True(err != nil)
+
+	out, err := ToUDLAisSpire(raw, "auto", defaultShipTypes, udl.AISIngestDataModeTEST, "U")
+	is.NoErr(err)
+	is.Equal(*out[0].ShipType, "Other")
+}
+
+func TestConfigure_MissingShipTypeMappingFile(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"shipTypeMappingFile":   filepath.Join(t.TempDir(), "missing.json"),
+	})
+	is.True(err != nil)
+}
@@ -3,6 +3,7 @@ package destination
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -18,10 +19,29 @@ const (
 )
 
 type Report struct {
+	Header        Header
 	SatelliteName string
 	Entries       []Entry
 }
 
+// Header holds the SP3 header records preceding the first epoch.
+type Header struct {
+	// Version is the SP3 version from the first line, 'c' or 'd'
+	Version byte
+	// PosVelFlag is 'P' for positions only or 'V' for positions and velocities
+	PosVelFlag byte
+	// CoordinateSystem is the coordinate system from the first line, e.g. IGS14
+	CoordinateSystem string
+	// Satellites are the satellite IDs listed in the + records
+	Satellites []string
+	// FileType is the file type from the first %c record, e.g. G for GPS only
+	FileType string
+	// TimeSystem is the time system from the first %c record, e.g. GPS or UTC
+	TimeSystem string
+	// Comments are the /* records without their prefix
+	Comments []string
+}
+
 type Entry struct {
 	Timestamp time.Time
 	Position  Position
@@ -29,6 +49,8 @@ type Entry struct {
 }
 
 type Position struct {
+	// SatelliteID is the 3 character satellite ID, e.g. 143 or G01
+	SatelliteID string
 	// Each satellite name is denoted by a 3-digit number representing the flight module (FM) number
 	FlightModuleNumber int
 	// X coords in km
@@ -42,6 +64,8 @@ type Position struct {
 }
 
 type Velocity struct {
+	// SatelliteID is the 3 character satellite ID, e.g. 143 or G01
+	SatelliteID string
 	// Each satellite name is denoted by a 3-digit number representing the flight module (FM) number
 	FlightModuleNumber int
 	// X velocity in decimeters/s
@@ -56,102 +80,174 @@ type Velocity struct {
 }
 
 func Parse(raw []byte) (Report, error) {
-	satName, err := extractSatelliteName(raw)
+	lines := splitLines(raw)
+	header, n, err := parseHeader(lines)
 	if err != nil {
 		return Report{}, err
 	}
 
-	entries, err := splitEntries(raw)
+	entries, err := splitEntries(lines[n:], n)
 	if err != nil {
 		return Report{}, err
 	}
 
 	return Report{
-		SatelliteName: satName,
+		Header:        header,
+		SatelliteName: header.satelliteName(),
 		Entries:       entries,
 	}, nil
 }
 
-// extractSatelliteName returns the Satellite Name
-func extractSatelliteName(raw []byte) (string, error) {
-	// setup line by line reader
-	reader := bytes.NewReader(raw)
-	scanner := bufio.NewScanner(reader)
-	lineNum := 0
-
-	// skip to Satellite Name
-	for lineNum < 21 {
-		if !scanner.Scan() {
-			return "", errors.New("invalid input")
-		}
-		lineNum++
+// splitLines returns the lines of raw without line endings.
+func splitLines(raw []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
 	}
-
-	// extract Satellite Name
-	fullLine := scanner.Text()
-	satName := strings.Replace(fullLine, "/* SATELLITE NAME: ", "", 1)
-	satName = strings.TrimSpace(satName)
-	return satName, nil
+	return lines
 }
 
-func splitEntries(raw []byte) ([]Entry, error) {
-	// setup line by line reader
-	reader := bytes.NewReader(raw)
-	scanner := bufio.NewScanner(reader)
+// parseHeader parses the header records of an SP3-c or SP3-d file and returns
+// the index of the first epoch line. The number of + and /* records differs
+// between versions and providers, so records are told apart by their type.
+func parseHeader(lines []string) (Header, int, error) {
+	if len(lines) == 0 || len(lines[0]) < 3 || lines[0][0] != '#' {
+		return Header{}, 0, errors.New("invalid input: missing SP3 version line")
+	}
+	header := Header{
+		Version:          lines[0][1],
+		PosVelFlag:       lines[0][2],
+		CoordinateSystem: strings.TrimSpace(field(lines[0], 46, 51)),
+	}
+	if header.Version != 'c' && header.Version != 'd' {
+		return Header{}, 0, fmt.Errorf("unsupported SP3 version %q", header.Version)
+	}
+	if header.PosVelFlag != 'P' && header.PosVelFlag != 'V' {
+		return Header{}, 0, fmt.Errorf("invalid SP3 position/velocity flag %q", header.PosVelFlag)
+	}
 
-	// skip past header
-	lineNum := 0
-	for lineNum < 22 {
-		if !scanner.Scan() {
-			return nil, errors.New("invalid input")
+	numSats := -1
+	var timeSystemRead bool
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "##"), strings.HasPrefix(line, "++"),
+			strings.HasPrefix(line, "%f"), strings.HasPrefix(line, "%i"):
+			// GPS week, accuracy and base records are not used
+		case strings.HasPrefix(line, "+"):
+			if numSats < 0 {
+				var err error
+				numSats, err = strconv.Atoi(strings.TrimSpace(field(line, 1, 6)))
+				if err != nil {
+					return Header{}, 0, fmt.Errorf("line %d: invalid number of satellites: %w", i+1, err)
+				}
+			}
+			for col := 9; col+3 <= len(line) && len(header.Satellites) < numSats; col += 3 {
+				header.Satellites = append(header.Satellites, satelliteID(line[col:col+3]))
+			}
+		case strings.HasPrefix(line, "%c"):
+			if !timeSystemRead {
+				header.FileType = strings.TrimSpace(field(line, 3, 5))
+				header.TimeSystem = strings.TrimSpace(field(line, 9, 12))
+				timeSystemRead = true
+			}
+		case strings.HasPrefix(line, "/*"):
+			header.Comments = append(header.Comments, strings.TrimSpace(strings.TrimPrefix(line, "/*")))
+		case strings.HasPrefix(line, "*"):
+			if numSats != len(header.Satellites) {
+				return Header{}, 0, fmt.Errorf("header lists %d of %d satellites", len(header.Satellites), numSats)
+			}
+			return header, i, nil
+		default:
+			return Header{}, 0, fmt.Errorf("line %d: unexpected SP3 header record %q", i+1, line)
 		}
-		lineNum++
 	}
+	return Header{}, 0, errors.New("invalid input: no epoch found")
+}
 
-	var entries []Entry
-
-	// there are more entries
-	for scanner.Scan() {
-		lineNum++
-		var entry Entry
+// field returns line[from:to] or the part of it within the line.
+func field(line string, from, to int) string {
+	if from >= len(line) {
+		return ""
+	}
+	if to > len(line) {
+		to = len(line)
+	}
+	return line[from:to]
+}
 
-		// extract epoch line
-		epochLine := scanner.Text()
-		if handleEOF(epochLine) {
-			return entries, nil
+// satelliteName returns the satellite name Spire puts in a comment record.
+func (h Header) satelliteName() string {
+	for _, c := range h.Comments {
+		if strings.HasPrefix(c, "SATELLITE NAME:") {
+			return strings.TrimSpace(strings.TrimPrefix(c, "SATELLITE NAME:"))
 		}
-		timestamp, err := parseEpoch(epochLine)
-		if err != nil {
-			log.Println("error on line: ", lineNum)
-			return nil, err
-		}
-		entry.Timestamp = timestamp
+	}
+	return ""
+}
 
-		// extract position
-		scanner.Scan() // next line
-		lineNum++
-		positionLine := scanner.Text()
-		position, err := parsePosition(positionLine)
-		if err != nil {
-			log.Println("error on line: ", lineNum)
-			return nil, err
-		}
-		entry.Position = position
+// extractSatelliteName returns the Satellite Name
+func extractSatelliteName(raw []byte) (string, error) {
+	header, _, err := parseHeader(splitLines(raw))
+	if err != nil {
+		return "", err
+	}
+	return header.satelliteName(), nil
+}
 
-		// extract velocity
-		scanner.Scan() // next line
-		lineNum++
-		velocityLine := scanner.Text()
-		velocity, err := parseVelocity(velocityLine)
-		if err != nil {
-			log.Println("error on line: ", lineNum)
-			return nil, err
+// splitEntries parses the epoch lines following the header, offset is the
+// number of header lines and only used in error messages. Every epoch holds a
+// position record for each satellite it covers, optionally followed by a
+// velocity record.
+func splitEntries(lines []string, offset int) ([]Entry, error) {
+	var (
+		entries []Entry
+		epoch   time.Time
+		inEpoch bool
+	)
+	for i, line := range lines {
+		lineNum := offset + i + 1
+		switch {
+		case handleEOF(line):
+			return entries, nil
+		case strings.HasPrefix(line, "*"):
+			timestamp, err := parseEpoch(line)
+			if err != nil {
+				log.Println("error on line: ", lineNum)
+				return nil, err
+			}
+			epoch = timestamp
+			inEpoch = true
+		case strings.HasPrefix(line, "P"):
+			if !inEpoch {
+				return nil, fmt.Errorf("line %d: position record before the first epoch", lineNum)
+			}
+			position, err := parsePosition(line)
+			if err != nil {
+				log.Println("error on line: ", lineNum)
+				return nil, err
+			}
+			entries = append(entries, Entry{Timestamp: epoch, Position: position})
+		case strings.HasPrefix(line, "V"):
+			velocity, err := parseVelocity(line)
+			if err != nil {
+				log.Println("error on line: ", lineNum)
+				return nil, err
+			}
+			// a velocity record follows the position record of its satellite
+			if len(entries) == 0 ||
+				entries[len(entries)-1].Position.SatelliteID != velocity.SatelliteID ||
+				entries[len(entries)-1].Velocity.SatelliteID != "" {
+				return nil, fmt.Errorf("line %d: velocity record of satellite %s without position", lineNum, velocity.SatelliteID)
+			}
+			entries[len(entries)-1].Velocity = velocity
+		case strings.HasPrefix(line, "EP"), strings.HasPrefix(line, "EV"), strings.TrimSpace(line) == "":
+			// correlation records are not used
+		default:
+			return nil, fmt.Errorf("line %d: unexpected SP3 record %q", lineNum, line)
 		}
-		entry.Velocity = velocity
-
-		entries = append(entries, entry)
 	}
-
 	return entries, nil
 }
 
@@ -174,56 +270,69 @@ func parseEpoch(line string) (time.Time, error) {
 }
 
 func parsePosition(line string) (Position, error) {
-	// strip P prefix
-	line = strings.TrimPrefix(line, "P")
-
-	// compact
-	line = compact(line)
-
-	// split 5 components FM number, X, Y, Z and clock error
-	components := strings.Split(line, " ")
-	if len(components) != 5 {
-		log.Printf("components(%d): %+v", len(components), components)
-		return Position{}, errors.New("invalid position line")
-	}
-	fmNumber, err := strconv.Atoi(components[0])
+	// split satellite ID from X, Y, Z and clock error
+	id, components, err := splitRecord(line, "P")
 	if err != nil {
-		return Position{}, err
+		return Position{}, errors.New("invalid position line")
 	}
 	return Position{
-		FlightModuleNumber: fmNumber,
-		X:                  components[1],
-		Y:                  components[2],
-		Z:                  components[3],
-		ClockError:         components[4],
+		SatelliteID:        id,
+		FlightModuleNumber: flightModuleNumber(id),
+		X:                  components[0],
+		Y:                  components[1],
+		Z:                  components[2],
+		ClockError:         components[3],
 	}, nil
 }
 
 func parseVelocity(line string) (Velocity, error) {
-	// strip V prefix
-	line = strings.TrimPrefix(line, "V")
-
-	// compact
-	line = compact(line)
+	// split satellite ID from X, Y, Z and clock error rate of change
+	id, components, err := splitRecord(line, "V")
+	if err != nil {
+		return Velocity{}, errors.New("invalid velocity line")
+	}
+	return Velocity{
+		SatelliteID:            id,
+		FlightModuleNumber:     flightModuleNumber(id),
+		X:                      components[0],
+		Y:                      components[1],
+		Z:                      components[2],
+		ClockErrorRateOfChange: components[3],
+	}, nil
+}
 
-	// split 5 components FM number, X, Y, Z and clock error
-	components := strings.Split(line, " ")
-	if len(components) != 5 {
+// splitRecord splits a P or V record into the satellite ID in columns 2-4 and
+// the four values following it. Standard deviations and flags after the
+// values are ignored.
+func splitRecord(line, prefix string) (string, []string, error) {
+	if !strings.HasPrefix(line, prefix) || len(line) < 4 {
+		return "", nil, errors.New("invalid record")
+	}
+	components := strings.Fields(line[4:])
+	if len(components) < 4 {
 		log.Printf("components(%d): %+v", len(components), components)
-		return Velocity{}, errors.New("invalid velocity line")
+		return "", nil, errors.New("invalid record")
+	}
+	return satelliteID(line[1:4]), components[:4], nil
+}
+
+// satelliteID normalizes a 3 character satellite ID, e.g. "G 1" to "G01".
+func satelliteID(id string) string {
+	id = strings.TrimSpace(id)
+	if len(id) == 3 && id[1] == ' ' {
+		id = id[:1] + "0" + id[2:]
 	}
-	fmNumber, err := strconv.Atoi(components[0])
+	return id
+}
+
+// flightModuleNumber returns the Spire flight module number of a numeric
+// satellite ID, or 0 for IDs of other constellations.
+func flightModuleNumber(id string) int {
+	fm, err := strconv.Atoi(id)
 	if err != nil {
-		return Velocity{}, err
+		return 0
 	}
-	return Velocity{
-		FlightModuleNumber:     fmNumber,
-		X:                      components[1],
-		Y:                      components[2],
-		Z:                      components[3],
-		ClockErrorRateOfChange: components[4],
-	}, nil
-	return Velocity{}, nil
+	return fm
 }
 
 // compact takes a string and removes duplicated (padded) spaces
@@ -232,44 +341,118 @@ func compact(in string) string {
 	return space.ReplaceAllString(in, " ")
 }
 
-func SP3cToUDL(report Report) (UDLReport, error) {
+// SP3ToUDL converts a report into one UDL report per satellite, in the order
+// the satellites first appear in. Missing velocities are derived from the
+// positions as configured by opts, NORAD IDs are resolved through norad.
+func SP3ToUDL(report Report, opts VelocityOptions, norad *NoradResolver) ([]UDLReport, error) {
+	var ids []string
+	bySatellite := make(map[string][]Entry)
+	for _, e := range report.Entries {
+		id := e.Position.SatelliteID
+		if _, ok := bySatellite[id]; !ok {
+			ids = append(ids, id)
+		}
+		bySatellite[id] = append(bySatellite[id], e)
+	}
+
+	reports := make([]UDLReport, 0, len(ids))
+	for _, id := range ids {
+		ur, err := SP3cToUDL(Report{
+			Header:        report.Header,
+			SatelliteName: report.SatelliteName,
+			Entries:       bySatellite[id],
+		}, opts, norad)
+		if err != nil {
+			return nil, fmt.Errorf("satellite %s: %w", id, err)
+		}
+		reports = append(reports, ur)
+	}
+	return reports, nil
+}
+
+// SP3cToUDL converts the report of a single satellite.
+func SP3cToUDL(report Report, opts VelocityOptions, norad *NoradResolver) (UDLReport, error) {
 	var uReport UDLReport
 
-	// the idOnOrbit is the FlightModuleNumber, so we take the first one and
-	// error if it changes while going through the entries
-	fm := report.Entries[0].Position.FlightModuleNumber
+	// the idOnOrbit is derived from the satellite, so we take the first one
+	// and error if it changes while going through the entries
+	id := report.Entries[0].Position.SatelliteID
+	var derived [][3]float64
 	for _, e := range report.Entries {
-		// if FlightModuleNumber has changed mid-report, return error
-		if e.Position.FlightModuleNumber != fm || e.Velocity.FlightModuleNumber != fm {
-			return UDLReport{}, errors.New("report contains multiple flight modules")
+		// if the satellite has changed mid-report, return error
+		if e.Position.SatelliteID != id || (e.Velocity.SatelliteID != "" && e.Velocity.SatelliteID != id) {
+			return UDLReport{}, errors.New("report contains multiple satellites")
+		}
+		if e.Velocity.SatelliteID == "" && derived == nil {
+			var err error
+			derived, err = deriveVelocities(report.Entries, opts)
+			if err != nil {
+				return UDLReport{}, err
+			}
 		}
+	}
+
+	for i, e := range report.Entries {
 		var udlEntry UDLEntry
 
 		// reformat date
+		udlEntry.Epoch = e.Timestamp
 		udlEntry.Timestamp = sp3cTimestampToUDL(e.Timestamp)
 
 		// reformat position
 		udlEntry.Position = sp3cPositionToUDL(e.Position)
 
-		// reformat velocity
-		var err error
-		udlEntry.Velocity, err = sp3cVelocityToUDL(e.Velocity)
-		if err != nil {
-			return UDLReport{}, err
+		// reformat velocity, or use the derived one for position-only epochs
+		if e.Velocity.SatelliteID == "" {
+			udlEntry.Velocity = velocityToUDL(derived[i])
+		} else {
+			var err error
+			udlEntry.Velocity, err = sp3cVelocityToUDL(e.Velocity)
+			if err != nil {
+				return UDLReport{}, err
+			}
 		}
 		uReport.Entries = append(uReport.Entries, udlEntry)
 	}
 
-	// map Spire Flight Module number to NORAD ID (for use in idOnOrbit)
-	nID, ok := fmMap()[fm]
-	if !ok {
-		return UDLReport{}, fmt.Errorf("no norad mapping for flight ID %d", fm)
+	// map the SP3 satellite ID or the Spire Flight Module number to NORAD ID
+	// (for use in idOnOrbit)
+	fm := report.Entries[0].Position.FlightModuleNumber
+	// the satellite name is that of the file, so it is only cross-checked
+	// and reported for files of a single satellite
+	var name string
+	if len(report.Header.Satellites) <= 1 {
+		name = report.SatelliteName
+	}
+	nID, mismatch, err := norad.SP3NoradID(context.Background(), id, fm, name)
+	if errors.Is(err, errNoNoradMapping) {
+		return UDLReport{}, fmt.Errorf("no norad mapping for satellite %s", id)
+	}
+	if err != nil {
+		return UDLReport{}, err
+	}
+	if mismatch != "" {
+		uReport.Comments = append(uReport.Comments, mismatch)
 	}
 	uReport.ID = strconv.Itoa(nID)
+	uReport.SatelliteID = id
+	uReport.ObjectName = name
+	uReport.CenterName = "EARTH"
+	uReport.RefFrame = sp3RefFrame(report.Header.CoordinateSystem)
+	uReport.TimeSystem = report.Header.TimeSystem
 
 	return uReport, nil
 }
 
+// sp3RefFrame returns the OEM reference frame of an SP3 coordinate system.
+// IGS frames, e.g. IGS14, are realizations of the ITRF.
+func sp3RefFrame(coordinateSystem string) string {
+	if strings.HasPrefix(coordinateSystem, "IGS") || strings.HasPrefix(coordinateSystem, "ITR") {
+		return "ITRF"
+	}
+	return coordinateSystem
+}
+
 func sp3cTimestampToUDL(t time.Time) string {
 	return t.Format(udlTimeLayout)
 }
@@ -305,6 +488,15 @@ func sp3cVelocityToUDL(v Velocity) (UDLVelocity, error) {
 	}, nil
 }
 
+// velocityToUDL formats a velocity in km/s.
+func velocityToUDL(v [3]float64) UDLVelocity {
+	return UDLVelocity{
+		X: fixedWidthFloat(v[0], 11),
+		Y: fixedWidthFloat(v[1], 11),
+		Z: fixedWidthFloat(v[2], 11),
+	}
+}
+
 // fixedWidthFloat returns a fixed width float64
 func fixedWidthFloat(num float64, width int) string {
 	whole := fmt.Sprintf("%.0f", num)
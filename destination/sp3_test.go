@@ -1,6 +1,8 @@
 package destination
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -83,6 +85,7 @@ func TestParsePosition(t *testing.T) {
 
 	positionLine := samplePositionLine()
 	expectedPosition := Position{
+		SatelliteID:        "143",
 		FlightModuleNumber: 143,
 		X:                  "-6657.474119",
 		Y:                  "-1525.599224",
@@ -100,6 +103,7 @@ func TestParseVelocity(t *testing.T) {
 
 	velocityLine := sampleVelocityLine()
 	expectedVelocity := Velocity{
+		SatelliteID:            "143",
 		FlightModuleNumber:     143,
 		X:                      "6927.842084",
 		Y:                      "17045.492627",
@@ -119,3 +123,143 @@ func samplePositionLine() string {
 func sampleVelocityLine() string {
 	return `V143   6927.842084  17045.492627 -74554.222095 999999.999999`
 }
+
+func sampleFileSP3d() []byte {
+	return []byte(`#dV2022 07 06 01 18 13.00000000       2 ORBIT IGS14 FIT  ABC
+## 2217 263893.00000000     1.00000000 59766 0.0543171297759
++    2   143144  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
++          0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
++          0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
++          0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
++          0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
++          0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+++         0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0  0
+%c L  cc UTC ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc
+%c cc cc ccc ccc cccc cccc cccc cccc ccccc ccccc ccccc ccccc
+%f  0.0000000  0.000000000  0.00000000000  0.000000000000000
+%f  0.0000000  0.000000000  0.00000000000  0.000000000000000
+%i    0    0    0    0      0      0      0      0         0
+%i    0    0    0    0      0      0      0      0         0
+/* PRECISE ORBIT PRODUCT
+/* GENERATED BY A THIRD PARTY PROVIDER
+/* WITH MORE THAN FOUR COMMENT LINES
+/* REFERENCE FRAME: IGS14
+/* CENTER OF MASS ORBIT
+/* END OF HEADER
+*  2022  7  6  1 18 13.00000000
+P143  -6658.162753  -1527.302901   -971.376727  -3827.755483
+V143   6844.820031  17028.031395 -74566.102286 999999.999999
+P144   5123.456789   3210.987654  -2468.135790    120.000000
+V144  -4521.123456  52310.654321  11234.567890 999999.999999
+*  2022  7  6  1 18 14.00000000
+P143  -6657.474119  -1525.599224   -978.832746  -3827.858503
+V143   6927.842084  17045.492627 -74554.222095 999999.999999
+P144   5122.999999   3216.218765  -2467.012345    120.000100
+V144  -4598.765432  52298.123456  11270.000000 999999.999999
+EOF
+`)
+}
+
+func TestParse_SP3c(t *testing.T) {
+	is := is.New(t)
+
+	report, err := Parse(sampleFile())
+	is.NoErr(err)
+	is.Equal(report.Header.Version, byte('c'))
+	is.Equal(report.Header.PosVelFlag, byte('V'))
+	is.Equal(report.Header.Satellites, []string{"143"})
+	is.Equal(report.Header.FileType, "G")
+	is.Equal(report.Header.TimeSystem, "GPS")
+	is.Equal(len(report.Header.Comments), 4)
+	is.Equal(report.SatelliteName, "LEMUR-2-JOHN-TREIRES")
+	is.Equal(len(report.Entries), 2)
+}
+
+func TestParse_SP3d(t *testing.T) {
+	is := is.New(t)
+
+	report, err := Parse(sampleFileSP3d())
+	is.NoErr(err)
+	is.Equal(report.Header.Version, byte('d'))
+	is.Equal(report.Header.Satellites, []string{"143", "144"})
+	is.Equal(report.Header.FileType, "L")
+	is.Equal(report.Header.TimeSystem, "UTC")
+	is.Equal(len(report.Header.Comments), 6)
+	is.Equal(report.SatelliteName, "") // third party files carry no Spire satellite name
+	is.Equal(len(report.Entries), 4)
+	is.Equal(report.Entries[0].Position.FlightModuleNumber, 143)
+	is.Equal(report.Entries[1].Position.SatelliteID, "144")
+	is.Equal(report.Entries[1].Velocity.SatelliteID, "144")
+}
+
+func TestParsePosition_ConstellationID(t *testing.T) {
+	is := is.New(t)
+
+	position, err := parsePosition("PG 1  -6657.474119  -1525.599224   -978.832746  -3827.858503  7  6  8 120")
+	is.NoErr(err)
+	is.Equal(position.SatelliteID, "G01")
+	is.Equal(position.FlightModuleNumber, 0)
+	is.Equal(position.ClockError, "-3827.858503") // standard deviations are ignored
+}
+
+func TestParse_VelocityWithoutPosition(t *testing.T) {
+	is := is.New(t)
+
+	raw := bytes.Replace(sampleFileSP3d(), []byte("V144  -4521"), []byte("V145  -4521"), 1)
+	_, err := Parse(raw)
+	is.True(err != nil)
+}
+
+func TestSP3ToUDL_MultipleSatellites(t *testing.T) {
+	is := is.New(t)
+
+	report, err := Parse(sampleFileSP3d())
+	is.NoErr(err)
+	reports, err := SP3ToUDL(report, VelocityOptions{}, defaultNoradResolver)
+	is.NoErr(err)
+	is.Equal(len(reports), 2) // one report per satellite
+	is.Equal(reports[0].SatelliteID, "143")
+	is.Equal(reports[0].ID, "48925")
+	is.Equal(reports[1].SatelliteID, "144")
+	is.Equal(reports[1].ID, "46502")
+	is.Equal(len(reports[0].Entries), 2)
+	is.Equal(len(reports[1].Entries), 2)
+	is.Equal(reports[1].Entries[1].Position.X, "5122.999999")
+
+	report.SatelliteName = "LEMUR-2-JOHN-TREIRES"
+	reports, err = SP3ToUDL(report, VelocityOptions{}, defaultNoradResolver)
+	is.NoErr(err)
+	is.Equal(reports[0].ObjectName, "") // the file name is not that of every satellite
+	is.Equal(reports[1].ObjectName, "")
+}
+
+func TestSP3ToUDL_UnmappedSatellite(t *testing.T) {
+	is := is.New(t)
+
+	report, err := Parse(bytes.ReplaceAll(sampleFileSP3d(), []byte("144"), []byte("999")))
+	is.NoErr(err)
+	_, err = SP3ToUDL(report, VelocityOptions{}, defaultNoradResolver)
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "satellite 999"))
+}
+
+func TestParse_UnsupportedVersion(t *testing.T) {
+	is := is.New(t)
+
+	raw := append([]byte("#a"), sampleFile()[2:]...)
+	_, err := Parse(raw)
+	is.True(err != nil)
+}
+
+func TestParse_UnexpectedHeaderRecord(t *testing.T) {
+	is := is.New(t)
+
+	raw := bytes.Replace(sampleFile(), []byte("%i    0"), []byte("?i    0"), 1)
+	_, err := Parse(raw)
+	is.True(err != nil)
+}
@@ -0,0 +1,254 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// Spire payload schema versions: 1 is the vessel of the Spire Vessels REST
+// API, 2 the vessel node of the Spire Maritime 2.0 GraphQL API.
+const (
+	spireSchemaAuto = "auto"
+	spireSchemaV1   = "1"
+	spireSchemaV2   = "2"
+)
+
+// SpireVesselV1 is a vessel of the Spire Vessels REST API.
+type SpireVesselV1 struct {
+	ID                string          `json:"id"`
+	UpdatedAt         string          `json:"updated_at"`
+	MMSI              int64           `json:"mmsi"`
+	IMO               int             `json:"imo"`
+	Name              string          `json:"name"`
+	CallSign          string          `json:"call_sign"`
+	Flag              string          `json:"flag"`
+	ShipType          string          `json:"ship_type"`
+	Class             string          `json:"class"`
+	A                 float64         `json:"a"`
+	B                 float64         `json:"b"`
+	C                 float64         `json:"c"`
+	D                 float64         `json:"d"`
+	Length            float64         `json:"length"`
+	Width             float64         `json:"width"`
+	LastKnownPosition SpirePositionV1 `json:"last_known_position"`
+	MostRecentVoyage  SpireVoyageV1   `json:"most_recent_voyage"`
+}
+
+// SpirePositionV1 is the last known position of a Spire Vessels REST API
+// vessel, its geometry is a GeoJSON point.
+type SpirePositionV1 struct {
+	Timestamp string `json:"timestamp"`
+	Geometry  struct {
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Accuracy           string   `json:"accuracy"`
+	CollectionType     string   `json:"collection_type"`
+	Course             *float64 `json:"course"`
+	Heading            *float64 `json:"heading"`
+	Maneuver           string   `json:"maneuver"`
+	NavigationalStatus string   `json:"navigational_status"`
+	ROT                *float64 `json:"rot"`
+	Speed              *float64 `json:"speed"`
+}
+
+// SpireVoyageV1 is the most recent voyage of a Spire Vessels REST API vessel.
+type SpireVoyageV1 struct {
+	Destination string  `json:"destination"`
+	Draught     float64 `json:"draught"`
+	ETA         string  `json:"eta"`
+	Timestamp   string  `json:"timestamp"`
+}
+
+// vesselData converts the vessel into the Maritime 2.0 shape.
+func (v SpireVesselV1) vesselData() VesselData {
+	vd := VesselData{
+		ID:              v.ID,
+		UpdateTimestamp: v.UpdatedAt,
+		StaticData: StaticData{
+			AISClass: v.Class,
+			CallSign: v.CallSign,
+			Dimensions: Dimensions{
+				A:      v.A,
+				B:      v.B,
+				C:      v.C,
+				D:      v.D,
+				Length: v.Length,
+				Width:  v.Width,
+			},
+			Flag:     v.Flag,
+			IMO:      v.IMO,
+			MMSI:     v.MMSI,
+			Name:     v.Name,
+			ShipType: v.ShipType,
+		},
+		LastPositionUpdate: LastPositionUpdate{
+			Accuracy:           v.LastKnownPosition.Accuracy,
+			CollectionType:     v.LastKnownPosition.CollectionType,
+			Course:             v.LastKnownPosition.Course,
+			Heading:            v.LastKnownPosition.Heading,
+			Maneuver:           v.LastKnownPosition.Maneuver,
+			NavigationalStatus: v.LastKnownPosition.NavigationalStatus,
+			ROT:                v.LastKnownPosition.ROT,
+			Speed:              v.LastKnownPosition.Speed,
+			Timestamp:          v.LastKnownPosition.Timestamp,
+		},
+		CurrentVoyage: CurrentVoyage{
+			Destination: v.MostRecentVoyage.Destination,
+			Draught:     v.MostRecentVoyage.Draught,
+			ETA:         v.MostRecentVoyage.ETA,
+			Timestamp:   v.MostRecentVoyage.Timestamp,
+		},
+	}
+	// GeoJSON coordinates are longitude, latitude
+	if c := v.LastKnownPosition.Geometry.Coordinates; len(c) >= 2 {
+		vd.LastPositionUpdate.Longitude = c[0]
+		vd.LastPositionUpdate.Latitude = c[1]
+	}
+	if vd.UpdateTimestamp == "" {
+		vd.UpdateTimestamp = v.LastKnownPosition.Timestamp
+	}
+	return vd
+}
+
+// spireSchema returns the canonical schema version, empty defaults to auto.
+func spireSchema(version string) string {
+	version = strings.ToLower(strings.TrimSpace(version))
+	if version == "" {
+		return spireSchemaAuto
+	}
+	return version
+}
+
+// ParseSpireVessels parses the vessels of a Spire payload of the schema
+// version, which is detected for auto. A payload is a single vessel or a
+// response holding a page of vessels: {"data": [...]} for version 1 and
+// {"data": {"vessels": {"nodes": [...]}}} for version 2.
+func ParseSpireVessels(raw []byte, version string) ([]VesselData, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	lower := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		lower[strings.ToLower(k)] = v
+	}
+
+	version = spireSchema(version)
+	if version == spireSchemaAuto {
+		version = detectSpireSchema(lower)
+	}
+
+	switch version {
+	case spireSchemaV1:
+		items := []json.RawMessage{raw}
+		if data, ok := lower["data"]; ok {
+			var page []json.RawMessage
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("spire schema 1 data: %w", err)
+			}
+			items = page
+		}
+		vessels := make([]VesselData, len(items))
+		for i, item := range items {
+			var v SpireVesselV1
+			if err := json.Unmarshal(item, &v); err != nil {
+				return nil, err
+			}
+			vessels[i] = v.vesselData()
+		}
+		return vessels, nil
+	case spireSchemaV2:
+		items := []json.RawMessage{raw}
+		if data, ok := lower["data"]; ok {
+			var page struct {
+				Vessels struct {
+					Nodes []json.RawMessage `json:"nodes"`
+				} `json:"vessels"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("spire schema 2 data: %w", err)
+			}
+			items = page.Vessels.Nodes
+		} else if nodes, ok := lower["nodes"]; ok {
+			var page []json.RawMessage
+			if err := json.Unmarshal(nodes, &page); err != nil {
+				return nil, fmt.Errorf("spire schema 2 nodes: %w", err)
+			}
+			items = page
+		}
+		vessels := make([]VesselData, len(items))
+		for i, item := range items {
+			if err := json.Unmarshal(item, &vessels[i]); err != nil {
+				return nil, err
+			}
+		}
+		return vessels, nil
+	default:
+		return nil, fmt.Errorf("unsupported spire schema version: %s", version)
+	}
+}
+
+// detectSpireSchema tells the schema versions apart by their keys, a payload
+// without known keys is read as version 2.
+func detectSpireSchema(fields map[string]json.RawMessage) string {
+	if data, ok := fields["data"]; ok {
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			return spireSchemaV1
+		}
+		return spireSchemaV2
+	}
+	for _, k := range []string{"last_known_position", "most_recent_voyage", "call_sign", "ship_type", "updated_at"} {
+		if _, ok := fields[k]; ok {
+			return spireSchemaV1
+		}
+	}
+	return spireSchemaV2
+}
+
+// ToUDLAisSpire transforms the vessels of a Spire payload of the schema
+// version.
+func ToUDLAisSpire(raw []byte, version string, shipTypes *ShipTypeMapping, dataMode udl.AISIngestDataMode, classificationMarking string) ([]udl.AISIngest, error) {
+	vessels, err := ParseSpireVessels(raw, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(vessels) == 0 {
+		return nil, errors.New("no vessels in spire payload")
+	}
+	out := make([]udl.AISIngest, len(vessels))
+	for i, v := range vessels {
+		if out[i], err = vesselToUDLAis(v, shipTypes, dataMode, classificationMarking); err != nil {
+			return nil, fmt.Errorf("vessel %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// checkSpireSchema validates the configured spire schema version.
+func checkSpireSchema(cfg Config) error {
+	switch spireSchema(cfg.SpireSchemaVersion) {
+	case spireSchemaAuto, spireSchemaV1, spireSchemaV2:
+		return nil
+	default:
+		return fmt.Errorf("unsupported spire schema version: %s", cfg.SpireSchemaVersion)
+	}
+}
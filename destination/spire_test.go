@@ -0,0 +1,180 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	spireV2Node = `{
+		"id": "a1",
+		"updateTimestamp": "2023-02-23T13:09:04.374Z",
+		"staticData": {
+			"mmsi": 538006489,
+			"name": "SEA_STAR",
+			"shipType": "TANKER_PRODUCT",
+			"callsign": "V7NA5",
+			"flag": "MH",
+			"imo": 9731913
+		},
+		"lastPositionUpdate": {
+			"latitude": 1.25,
+			"longitude": 103.8,
+			"accuracy": "HIGH",
+			"collectionType": "SATELLITE",
+			"course": 96.5,
+			"heading": 97,
+			"maneuver": "SPECIAL_MANEUVER",
+			"navigationalStatus": "UNDER_WAY_USING_ENGINE",
+			"rot": -2.5,
+			"speed": 10
+		}
+	}`
+
+	spireV2Response = `{"data": {"vessels": {"pageInfo": {"hasNextPage": false}, "nodes": [` + spireV2Node + `,
+		{"id": "a2", "updateTimestamp": "2023-02-23T13:10:00Z", "staticData": {"mmsi": 1}, "lastPositionUpdate": {"speed": 0, "maneuver": "NOT_AVAILABLE"}}
+	]}}}`
+
+	spireV1Vessel = `{
+		"id": "b1",
+		"updated_at": "2019-10-24T14:22:01.000Z",
+		"mmsi": 538006489,
+		"imo": 9731913,
+		"name": "SEA STAR",
+		"call_sign": "V7NA5",
+		"flag": "MH",
+		"ship_type": "TANKER_PRODUCT",
+		"class": "A",
+		"length": 183,
+		"width": 32,
+		"last_known_position": {
+			"timestamp": "2019-10-24T14:20:00.000Z",
+			"geometry": {"type": "Point", "coordinates": [103.8, 1.25]},
+			"collection_type": "terrestrial",
+			"course": 96.5,
+			"heading": 97,
+			"rot": 0,
+			"speed": 12.5
+		},
+		"most_recent_voyage": {"destination": "SINGAPORE", "draught": 9.8}
+	}`
+)
+
+func TestParseSpireVessels(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		version string
+		ids     []string
+	}{
+		{name: "v2 node", raw: spireV2Node, version: "auto", ids: []string{"a1"}},
+		{name: "v2 response", raw: spireV2Response, version: "auto", ids: []string{"a1", "a2"}},
+		{name: "v2 nodes", raw: `{"nodes": [` + spireV2Node + `]}`, version: "2", ids: []string{"a1"}},
+		{name: "v1 vessel", raw: spireV1Vessel, version: "auto", ids: []string{"b1"}},
+		{name: "v1 response", raw: `{"paging": {}, "data": [` + spireV1Vessel + `]}`, version: "", ids: []string{"b1"}},
+		{name: "configured v1", raw: spireV1Vessel, version: "1", ids: []string{"b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			vessels, err := ParseSpireVessels([]byte(tt.raw), tt.version)
+			is.NoErr(err)
+			var ids []string
+			for _, v := range vessels {
+				ids = append(ids, v.ID)
+			}
+			is.Equal(ids, tt.ids)
+		})
+	}
+}
+
+func TestParseSpireVessels_UnsupportedVersion(t *testing.T) {
+	is := is.New(t)
+	_, err := ParseSpireVessels([]byte(spireV2Node), "3")
+	is.True(err != nil)
+}
+
+func TestToUDLAisSpire_V2(t *testing.T) {
+	is := is.New(t)
+
+	out, err := ToUDLAisSpire([]byte(spireV2Response), "auto", defaultShipTypes, udl.AISIngestDataModeTEST, "U")
+	is.NoErr(err)
+	is.Equal(len(out), 2)
+
+	ais := out[0]
+	is.Equal(ais.Ts, time.Date(2023, 2, 23, 13, 9, 4, 374000000, time.UTC))
+	is.Equal(*ais.ShipName, "SEA STAR")
+	is.Equal(*ais.ShipType, "Tanker Product")
+	is.Equal(*ais.NavStatus, "UNDER WAY USING ENGINE")
+	is.True(math.Abs(*ais.Speed-10*knotsToKmh) < 1e-9)
+	is.Equal(*ais.RateOfTurn, -2.5)
+	is.Equal(*ais.SpecialManeuver, true)
+	is.Equal(ais.Origin, nil) // the collection type is not the originating organization
+
+	// speed 0 is reported, an unavailable maneuver is not
+	is.Equal(out[1].Ts, time.Date(2023, 2, 23, 13, 10, 0, 0, time.UTC))
+	is.Equal(*out[1].Speed, 0.0)
+	is.Equal(out[1].SpecialManeuver, nil)
+	is.Equal(out[1].RateOfTurn, nil)
+}
+
+func TestToUDLAisSpire_V1(t *testing.T) {
+	is := is.New(t)
+
+	out, err := ToUDLAisSpire([]byte(spireV1Vessel), "auto", defaultShipTypes, udl.AISIngestDataModeTEST, "U")
+	is.NoErr(err)
+	is.Equal(len(out), 1)
+
+	ais := out[0]
+	is.Equal(*ais.Id, "b1")
+	is.Equal(ais.Ts, time.Date(2019, 10, 24, 14, 22, 1, 0, time.UTC))
+	is.Equal(*ais.Mmsi, int64(538006489))
+	is.Equal(*ais.ShipName, "SEA STAR")
+	is.Equal(*ais.ShipType, "Tanker Product")
+	is.Equal(*ais.CallSign, "V7NA5")
+	is.Equal(*ais.Lat, 1.25)
+	is.Equal(*ais.Lon, 103.8)
+	is.Equal(*ais.Length, 183.0)
+	is.True(math.Abs(*ais.Speed-12.5*knotsToKmh) < 1e-9)
+	is.Equal(*ais.RateOfTurn, 0.0)
+	is.Equal(ais.Origin, nil)
+	is.Equal(*ais.Draught, 9.8)
+}
+
+func TestToUDLAisSpire_EmptyPage(t *testing.T) {
+	is := is.New(t)
+	_, err := ToUDLAisSpire([]byte(`{"data": {"vessels": {"nodes": []}}}`), "auto", defaultShipTypes, udl.AISIngestDataModeTEST, "U")
+	is.True(err != nil)
+}
+
+func TestConfigure_UnsupportedSpireSchemaVersion(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"spireSchemaVersion":    "3",
+	})
+	is.True(err != nil)
+}
@@ -0,0 +1,359 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	// tleLineLength is the length of a TLE line including its checksum.
+	tleLineLength = 69
+
+	// earthMu is the standard gravitational parameter of the earth in km^3/s^2
+	// the UDL derives the semi-major axis, apogee and perigee with.
+	earthMu = 398600.4418
+
+	tleSubmitBulk     = "bulk"
+	tleSubmitFiledrop = "filedrop"
+)
+
+// TLE is a two-line element set with the optional name line of a 3LE.
+type TLE struct {
+	Name  string
+	Line1 string
+	Line2 string
+
+	SatNo int
+	// Classification is U, C or S
+	Classification byte
+	// IntlDesignator is the international designator, e.g. 98067A
+	IntlDesignator string
+	Epoch          time.Time
+	// MeanMotionDot is the 1st derivative of the mean motion in rev/day^2
+	MeanMotionDot float64
+	// MeanMotionDDot is the 2nd derivative of the mean motion in rev/day^3
+	MeanMotionDDot float64
+	// BStar is the drag term in inverse earth radii
+	BStar        float64
+	EphemType    int
+	ElementSetNo int
+	// Inclination, RAAN, ArgOfPerigee and MeanAnomaly are in degrees
+	Inclination  float64
+	RAAN         float64
+	Eccentricity float64
+	ArgOfPerigee float64
+	MeanAnomaly  float64
+	// MeanMotion is in revolutions per day
+	MeanMotion float64
+	RevNo      int
+}
+
+// String returns the TLE as 2 or 3 lines.
+func (t TLE) String() string {
+	if t.Name == "" {
+		return t.Line1 + "\n" + t.Line2 + "\n"
+	}
+	return t.Name + "\n" + t.Line1 + "\n" + t.Line2 + "\n"
+}
+
+// ParseTLEs parses one or many TLEs, each optionally preceded by a name line.
+func ParseTLEs(raw []byte) ([]TLE, error) {
+	var (
+		tles []TLE
+		name string
+	)
+	lines := splitLines(raw)
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, "1 "):
+			if i+1 == len(lines) {
+				return nil, fmt.Errorf("line %d: TLE line 1 without line 2", i+1)
+			}
+			tle, err := parseTLE(name, line, strings.TrimRight(lines[i+1], " \r"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			tles = append(tles, tle)
+			name = ""
+			i++
+		default:
+			if name != "" {
+				return nil, fmt.Errorf("line %d: expected TLE line 1 after name %q", i+1, name)
+			}
+			// 3LE name lines may be prefixed with a 0
+			name = strings.TrimSpace(strings.TrimPrefix(line, "0 "))
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("name %q without TLE", name)
+	}
+	if len(tles) == 0 {
+		return nil, errors.New("no TLE found")
+	}
+	return tles, nil
+}
+
+// ElsetIngest returns the elset of the TLE. The elset has no writable field
+// for the element set number; line1 and line2 are derived by the UDL and
+// ignored on create, so the number is lost on this path.
+func (t TLE) ElsetIngest(dataMode udl.ElsetIngestDataMode, classificationMarking, source string) udl.ElsetIngest {
+	idOnOrbit := strconv.Itoa(t.SatNo)
+	satNo := int32(t.SatNo)
+	ephemType := int32(t.EphemType)
+	revNo := int64(t.RevNo)
+	sma := semiMajorAxis(t.MeanMotion)
+	period := 1440 / t.MeanMotion
+	apogee := sma * (1 + t.Eccentricity)
+	perigee := sma * (1 - t.Eccentricity)
+
+	return udl.ElsetIngest{
+		ClassificationMarking: classificationMarking,
+		DataMode:              dataMode,
+		Source:                source,
+		IdOnOrbit:             &idOnOrbit,
+		SatNo:                 &satNo,
+		Epoch:                 t.Epoch,
+		Inclination:           &t.Inclination,
+		Raan:                  &t.RAAN,
+		Eccentricity:          &t.Eccentricity,
+		ArgOfPerigee:          &t.ArgOfPerigee,
+		MeanAnomaly:           &t.MeanAnomaly,
+		MeanMotion:            &t.MeanMotion,
+		MeanMotionDot:         &t.MeanMotionDot,
+		MeanMotionDDot:        &t.MeanMotionDDot,
+		BStar:                 &t.BStar,
+		EphemType:             &ephemType,
+		RevNo:                 &revNo,
+		SemiMajorAxis:         &sma,
+		Period:                &period,
+		Apogee:                &apogee,
+		Perigee:               &perigee,
+	}
+}
+
+// tleElsets returns the elsets of the TLEs of all records with the configured
+// data mode, classification marking and source.
+func tleElsets(cfg Config, records [][]TLE) []udl.ElsetIngest {
+	var elsets []udl.ElsetIngest
+	for _, tles := range records {
+		for _, tle := range tles {
+			elsets = append(elsets, tle.ElsetIngest(elsetDataMode(cfg), cfg.ClassificationMarking, elsetSource(cfg)))
+		}
+	}
+	return elsets
+}
+
+// semiMajorAxis returns the semi-major axis in km of an orbit with the mean
+// motion in revolutions per day.
+func semiMajorAxis(meanMotion float64) float64 {
+	n := meanMotion * 2 * math.Pi / 86400
+	return math.Cbrt(earthMu / (n * n))
+}
+
+// tleSubmitMethod returns the canonical submit method, empty defaults to bulk.
+func tleSubmitMethod(cfg Config) string {
+	method := strings.ToLower(strings.TrimSpace(cfg.TLESubmitMethod))
+	if method == "" {
+		return tleSubmitBulk
+	}
+	return method
+}
+
+// checkTLE validates the configured TLE submit method.
+func checkTLE(cfg Config) error {
+	switch tleSubmitMethod(cfg) {
+	case tleSubmitBulk, tleSubmitFiledrop:
+		return nil
+	default:
+		return fmt.Errorf("unsupported TLE submit method: %s", cfg.TLESubmitMethod)
+	}
+}
+
+// tleParams returns the createBulkFromTLE parameters of the config.
+func tleParams(cfg Config) udl.CreateBulkFromTLEParams {
+	return udl.CreateBulkFromTLEParams{
+		DataMode:    string(elsetDataMode(cfg)),
+		MakeCurrent: cfg.TLEMakeCurrent,
+		Source:      elsetSource(cfg),
+	}
+}
+
+func parseTLE(name, line1, line2 string) (TLE, error) {
+	for n, line := range []string{line1, line2} {
+		if len(line) != tleLineLength {
+			return TLE{}, fmt.Errorf("TLE line %d has %d characters, want %d", n+1, len(line), tleLineLength)
+		}
+		if line[0] != byte('1'+n) {
+			return TLE{}, fmt.Errorf("TLE line %d starts with %q", n+1, line[0])
+		}
+		if err := checkTLEChecksum(line); err != nil {
+			return TLE{}, fmt.Errorf("TLE line %d: %w", n+1, err)
+		}
+	}
+
+	tle := TLE{
+		Name:           name,
+		Line1:          line1,
+		Line2:          line2,
+		Classification: line1[7],
+		IntlDesignator: strings.TrimSpace(line1[9:17]),
+	}
+	p := tleParser{}
+	tle.SatNo = p.satNo(line1[2:7])
+	if satNo2 := p.satNo(line2[2:7]); p.err == nil && satNo2 != tle.SatNo {
+		return TLE{}, fmt.Errorf("TLE lines have different satellite numbers %d and %d", tle.SatNo, satNo2)
+	}
+	tle.Epoch = p.epoch(line1[18:32])
+	tle.MeanMotionDot = p.float(line1[33:43])
+	tle.MeanMotionDDot = p.exp(line1[44:52])
+	tle.BStar = p.exp(line1[53:61])
+	tle.EphemType = p.int(line1[62:63])
+	tle.ElementSetNo = p.int(line1[64:68])
+	tle.Inclination = p.float(line2[8:16])
+	tle.RAAN = p.float(line2[17:25])
+	tle.Eccentricity = p.float("." + line2[26:33])
+	tle.ArgOfPerigee = p.float(line2[34:42])
+	tle.MeanAnomaly = p.float(line2[43:51])
+	tle.MeanMotion = p.float(line2[52:63])
+	tle.RevNo = p.int(line2[63:68])
+	if p.err != nil {
+		return TLE{}, p.err
+	}
+	if tle.MeanMotion <= 0 {
+		return TLE{}, fmt.Errorf("invalid TLE mean motion %v", tle.MeanMotion)
+	}
+	return tle, nil
+}
+
+// checkTLEChecksum verifies the modulo 10 checksum in the last column, the sum
+// of all digits with each minus sign counting as 1.
+func checkTLEChecksum(line string) error {
+	sum := 0
+	for _, c := range line[:tleLineLength-1] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	want := line[tleLineLength-1]
+	if want < '0' || want > '9' || int(want-'0') != sum%10 {
+		return fmt.Errorf("checksum %q does not match %d", want, sum%10)
+	}
+	return nil
+}
+
+// tleParser parses TLE fields and keeps the first error.
+type tleParser struct {
+	err error
+}
+
+func (p *tleParser) fail(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (p *tleParser) float(field string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+	if err != nil {
+		p.fail("invalid TLE field %q", field)
+	}
+	return v
+}
+
+func (p *tleParser) int(field string) int {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(field)
+	if err != nil {
+		p.fail("invalid TLE field %q", field)
+	}
+	return v
+}
+
+// exp parses a field with an implied decimal point and exponent, e.g.
+// " 10270-3" is 0.10270e-3.
+func (p *tleParser) exp(field string) float64 {
+	field = strings.TrimSpace(field)
+	if len(field) < 2 {
+		p.fail("invalid TLE field %q", field)
+		return 0
+	}
+	mantissa, exponent := field[:len(field)-2], field[len(field)-2:]
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") || strings.HasPrefix(mantissa, "+") {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	v, err := strconv.ParseFloat(sign+"."+mantissa+"e"+exponent, 64)
+	if err != nil {
+		p.fail("invalid TLE field %q", field)
+	}
+	return v
+}
+
+// satNo parses a satellite number, including the Alpha-5 form where the first
+// digit is replaced by a letter (A=10, skipping I and O).
+func (p *tleParser) satNo(field string) int {
+	field = strings.TrimSpace(field)
+	if field != "" && field[0] >= 'A' && field[0] <= 'Z' && field[0] != 'I' && field[0] != 'O' {
+		prefix := int(field[0]-'A') + 10
+		if field[0] > 'I' {
+			prefix--
+		}
+		if field[0] > 'O' {
+			prefix--
+		}
+		return prefix*10000 + p.int(field[1:])
+	}
+	return p.int(field)
+}
+
+// epoch parses a two digit year followed by the fractional day of the year,
+// years 57 to 99 are in the 20th century.
+func (p *tleParser) epoch(field string) time.Time {
+	year := p.int(field[:2])
+	day := p.float(field[2:])
+	if p.err != nil {
+		return time.Time{}
+	}
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	daysInYear := start.AddDate(1, 0, 0).Sub(start).Hours() / 24
+	if day < 1 || day >= daysInYear+1 {
+		p.fail("invalid TLE epoch day %v of %d", day, year)
+		return time.Time{}
+	}
+	// round to microseconds, the precision of UDL epochs
+	return start.Add(time.Duration((day - 1) * 24 * float64(time.Hour))).Round(time.Microsecond)
+}
@@ -0,0 +1,233 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	issLine1 = "1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927"
+	issLine2 = "2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537"
+
+	sampleTLEs = "ISS (ZARYA)\n" + issLine1 + "\n" + issLine2 + "\n" +
+		"1 48925U 21059A   23150.50000000  .00001264  00000+0  10270-3 0  9996\n" +
+		"2 48925  97.5000 120.1234 0001500  90.0000 270.1234 15.20000000 12346\n"
+)
+
+// withChecksum replaces the checksum of a modified TLE line.
+func withChecksum(line string) string {
+	sum := 0
+	for _, c := range line[:68] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return line[:68] + string(rune('0'+sum%10))
+}
+
+func TestParseTLEs(t *testing.T) {
+	is := is.New(t)
+
+	tles, err := ParseTLEs([]byte(sampleTLEs))
+	is.NoErr(err)
+	is.Equal(len(tles), 2)
+
+	iss := tles[0]
+	is.Equal(iss.Name, "ISS (ZARYA)")
+	is.Equal(iss.SatNo, 25544)
+	is.Equal(iss.Classification, byte('U'))
+	is.Equal(iss.IntlDesignator, "98067A")
+	is.Equal(iss.Epoch, time.Date(2008, 9, 20, 12, 25, 40, 104192000, time.UTC))
+	is.Equal(iss.MeanMotionDot, -0.00002182)
+	is.Equal(iss.MeanMotionDDot, 0.0)
+	is.Equal(iss.BStar, -0.11606e-4)
+	is.Equal(iss.ElementSetNo, 292)
+	is.Equal(iss.Inclination, 51.6416)
+	is.Equal(iss.RAAN, 247.4627)
+	is.Equal(iss.Eccentricity, 0.0006703)
+	is.Equal(iss.ArgOfPerigee, 130.5360)
+	is.Equal(iss.MeanAnomaly, 325.0288)
+	is.Equal(iss.MeanMotion, 15.72125391)
+	is.Equal(iss.RevNo, 56353)
+
+	is.Equal(tles[1].Name, "")
+	is.Equal(tles[1].SatNo, 48925)
+	is.Equal(tles[1].BStar, 0.10270e-3)
+}
+
+func TestParseTLEs_Alpha5(t *testing.T) {
+	is := is.New(t)
+
+	tles, err := ParseTLEs([]byte(withChecksum("1 A5544"+issLine1[7:]) + "\n" + withChecksum("2 A5544"+issLine2[7:])))
+	is.NoErr(err)
+	is.Equal(tles[0].SatNo, 105544)
+}
+
+func TestParseTLEs_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "empty", raw: "\n"},
+		{name: "checksum", raw: issLine1[:68] + "0\n" + issLine2},
+		{name: "missing line 2", raw: "ISS (ZARYA)\n" + issLine1},
+		{name: "short line", raw: issLine1 + "\n" + issLine2[:60]},
+		{name: "swapped lines", raw: issLine2 + "\n" + issLine1},
+		{name: "different satellites", raw: issLine1 + "\n" + withChecksum("2 25545"+issLine2[7:])},
+		{name: "epoch day 0", raw: withChecksum(issLine1[:20]+"000"+issLine1[23:]) + "\n" + issLine2},
+		{name: "epoch after end of year", raw: withChecksum(issLine1[:18]+"09366"+issLine1[23:]) + "\n" + issLine2},
+		{name: "name without TLE", raw: sampleTLEs + "ISS (ZARYA)\n"},
+		{name: "zero mean motion", raw: issLine1 + "\n" + withChecksum(issLine2[:52]+" 0.00000000"+issLine2[63:])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := ParseTLEs([]byte(tt.raw))
+			is.True(err != nil)
+		})
+	}
+}
+
+func TestTLE_ElsetIngest(t *testing.T) {
+	is := is.New(t)
+	tles, err := ParseTLEs([]byte(issLine1 + "\n" + issLine2))
+	is.NoErr(err)
+
+	elset := tles[0].ElsetIngest(udl.ElsetIngestDataModeREAL, "U//FOUO", "Spire")
+	is.Equal(elset.DataMode, udl.ElsetIngestDataModeREAL)
+	is.Equal(elset.ClassificationMarking, "U//FOUO")
+	is.Equal(elset.Source, "Spire")
+	is.Equal(*elset.IdOnOrbit, "25544")
+	is.Equal(*elset.SatNo, int32(25544))
+	is.Equal(elset.Epoch, tles[0].Epoch)
+	is.Equal(*elset.Inclination, 51.6416)
+	is.Equal(*elset.Raan, 247.4627)
+	is.Equal(*elset.Eccentricity, 0.0006703)
+	is.Equal(*elset.ArgOfPerigee, 130.5360)
+	is.Equal(*elset.MeanAnomaly, 325.0288)
+	is.Equal(*elset.MeanMotion, 15.72125391)
+	is.Equal(*elset.MeanMotionDot, -0.00002182)
+	is.Equal(*elset.MeanMotionDDot, 0.0)
+	is.Equal(*elset.BStar, -0.11606e-4)
+	is.Equal(*elset.EphemType, int32(0))
+	is.Equal(*elset.RevNo, int64(56353))
+	is.Equal(elset.Line1, nil) // read only in the UDL
+	is.Equal(elset.Line2, nil)
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+	is.True(near(*elset.SemiMajorAxis, 6730.960677))
+	is.True(near(*elset.Period, 91.595747))
+	is.True(near(*elset.Apogee, 6735.472440))
+	is.True(near(*elset.Perigee, 6726.448914))
+}
+
+func TestWrite_TLE(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "TLE"
+	dest.Config.DataMode = "REAL"
+	dest.Config.TLEMakeCurrent = true
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(sampleTLEs)}},
+		{Payload: sdk.Change{After: sdk.RawData(issLine1 + "\r\n" + issLine2)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	is.Equal(client.tleParams, []udl.CreateBulkFromTLEParams{{DataMode: "REAL", MakeCurrent: true, Source: "Spire"}})
+	is.Equal(client.tleBodies, []string{sampleTLEs + issLine1 + "\n" + issLine2 + "\n"})
+}
+
+func TestWrite_TLEChunkFailure(t *testing.T) {
+	is := is.New(t)
+	// the second TLE of the first record fails
+	client := &mockClient{fail: map[string]mockFailure{mockTLE: {at: 1, status: http.StatusBadRequest}}}
+	dest := Destination{client: client}
+	dest.Config.DataType = "TLE"
+	dest.Config.MaxRecordsPerRequest = 1
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(sampleTLEs)}},
+		{Payload: sdk.Change{After: sdk.RawData(issLine1 + "\n" + issLine2)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.True(err != nil)
+	is.Equal(n, 0)
+	is.True(strings.HasPrefix(client.tleBodies[0], "ISS (ZARYA)\n"))
+}
+
+func TestWrite_TLEMaxBytes(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "TLE"
+	// exactly the text of both TLEs, their JSON strings would be longer
+	dest.Config.MaxBytesPerRequest = len(sampleTLEs)
+	records := []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleTLEs)}}}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(client.tleBodies, []string{sampleTLEs}) // sent in a single request
+}
+
+func TestWrite_TLEFiledrop(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "TLE"
+	dest.Config.TLESubmitMethod = "filedrop"
+	dest.Config.ClassificationMarking = "U"
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(sampleTLEs)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(len(client.elsets), 2)
+	is.Equal(*client.elsets[1].IdOnOrbit, "48925")
+	is.Equal(client.elsets[1].DataMode, udl.ElsetIngestDataModeTEST)
+	is.Equal(client.elsets[1].ClassificationMarking, "U")
+	is.Equal(client.elsets[1].Source, "Spire")
+}
+
+func TestConfigure_UnsupportedTLESubmitMethod(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "TLE",
+		"tleSubmitMethod":       "ftp",
+	})
+	is.True(err != nil)
+}
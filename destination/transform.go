@@ -15,10 +15,13 @@
 package destination
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"slices"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
@@ -71,37 +74,31 @@ var cargoTypeMapping = map[string]string{
 
 var engagedIn = []string{"ANTI POLLUTION", "DIVE VESSEL", "DREDGER", "HIGH SPEED CRAFT", "MILITARY OPS", "OTHER", "PLEASURE CRAFT", "SAILING"}
 var specialCraft = []string{"LAW ENFORCEMENT", "MEDICAL TRANS", "PILOT VESSEL", "PORT TENDER", "SEARCH AND RESCUE", "SPECIAL CRAFT"}
-var cargoType = []string{"CAR CARRIER", "DRY BULK", "GENERAL CARGO", "REEFER", "ROLL ON ROLL OFF"}
-
-func setAISShipSubType(vesselData VesselData, ais *udl.AISIngest) {
-	if slices.Contains(engagedIn, vesselData.StaticData.ShipType) {
-		sdk.Logger(context.Background()).Debug().Msgf("%s is in the engagedIn\n", vesselData.StaticData.ShipType)
-		if vesselData.StaticData.ShipSubType != "" {
-			sdk.Logger(context.Background()).Debug().Msgf("EngagedIn ShipSubType: %s", vesselData.StaticData.ShipSubType)
-			ais.EngagedIn = &vesselData.StaticData.ShipSubType
-		}
-	}
-	if slices.Contains(specialCraft, vesselData.StaticData.ShipType) {
-		// sdk.Logger(context.Background()).Debug().Msgf("%s is in the specialCraft\n", vesselData.StaticData.ShipType)
-		specialCraftValue := toTitleCase(vesselData.StaticData.ShipType)
-		// sdk.Logger(context.Background()).Debug().Msgf("specialCraftValue - %s\n", specialCraftValue)
-		ais.SpecialCraft = &specialCraftValue
-	}
-	if slices.Contains(cargoType, vesselData.StaticData.ShipType) {
-		// sdk.Logger(context.Background()).Debug().Msgf("%s is in the cargoType\n", vesselData.StaticData.ShipType)
-		cargoTypeValue := cargoTypeMapping[vesselData.StaticData.ShipType]
-		// sdk.Logger(context.Background()).Debug().Msgf("cargoTypeValue - %s\n", cargoTypeValue)
-		ais.CargoType = &cargoTypeValue
-	}
-}
 
+// ToUDLAis transforms a single Spire Maritime 2.0 vessel with the built-in
+// ship type mappings.
 func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking string) (udl.AISIngest, error) {
 	var vesselData VesselData
 	err := json.Unmarshal(raw, &vesselData)
 	if err != nil {
 		return udl.AISIngest{}, err
 	}
+	return vesselToUDLAis(vesselData, defaultShipTypes, dataMode, classificationMarking)
+}
+
+// parseSpireTime parses a Spire timestamp. Spire timestamps have millisecond
+// precision, RFC 3339 covers others.
+func parseSpireTime(s string) (time.Time, error) {
+	ts, err := time.Parse("2006-01-02T15:04:05.999Z", s)
+	if err != nil {
+		ts, err = time.Parse(time.RFC3339Nano, s)
+	}
+	return ts, err
+}
 
+// vesselToUDLAis maps a Spire vessel of any schema version into the UDL
+// model.
+func vesselToUDLAis(vesselData VesselData, shipTypes *ShipTypeMapping, dataMode udl.AISIngestDataMode, classificationMarking string) (udl.AISIngest, error) {
 	// Replace underscores with spaces in vesselData strings
 	replaceUnderscoresInStruct(&vesselData)
 
@@ -109,10 +106,8 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 
 	sdk.Logger(context.Background()).Debug().Msgf("vesselData: %+v", vesselData)
 
-	layout := "2006-01-02T15:04:05.999Z"
-
-	ts, tsErr := time.Parse(layout, vesselData.UpdateTimestamp)
-	if tsErr != nil {
+	ts, err := parseSpireTime(vesselData.UpdateTimestamp)
+	if err != nil {
 		sdk.Logger(context.Background()).Err(err).Msgf("Error parsing timestamp")
 		return udl.AISIngest{}, err
 	}
@@ -129,13 +124,13 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 		ais.ShipName = &vesselData.StaticData.Name
 	}
 	if vesselData.StaticData.ShipType != "" {
-		navCenShipType := spireToNavcenShipTypeMapping[vesselData.StaticData.ShipType]
-		ais.ShipType = &navCenShipType
+		err := shipTypes.setAISShipType(vesselData.StaticData.ShipType, vesselData.StaticData.ShipSubType, &ais)
+		if err != nil {
+			return udl.AISIngest{}, err
+		}
 
 		sdk.Logger(context.Background()).Info().Msgf("ais shipType: %s", *ais.ShipType)
 		sdk.Logger(context.Background()).Info().Msgf("ShipType: %s", vesselData.StaticData.ShipType)
-		setAISShipSubType(vesselData, &ais)
-
 	}
 	if vesselData.StaticData.CallSign != "" {
 		ais.CallSign = &vesselData.StaticData.CallSign
@@ -144,8 +139,10 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 	if vesselData.StaticData.Flag != "" {
 		ais.VesselFlag = &vesselData.StaticData.Flag
 	}
-	if vesselData.StaticData.Flag != "" {
-		ais.VesselFlag = &vesselData.StaticData.Flag
+
+	if vesselData.StaticData.IMO != 0 {
+		imo := int64(vesselData.StaticData.IMO)
+		ais.Imon = &imo
 	}
 
 	if vesselData.LastPositionUpdate.Latitude != 0.0 {
@@ -157,17 +154,35 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 	}
 	hiAccuracy := vesselData.LastPositionUpdate.Accuracy == "HIGH"
 	ais.PosHiAccuracy = &hiAccuracy
+	// CollectionType, whether the position was received by satellite or
+	// terrestrial stations, is not submitted: the UDL AIS model has no field
+	// for the receiving network. origNetwork is set by the UDL itself and
+	// origin names the organization that produced the data.
+
+	// a heading or course of 0 is north, only missing values are left out
+	ais.TrueHeading = vesselData.LastPositionUpdate.Heading
+	ais.Course = vesselData.LastPositionUpdate.Course
 
-	if vesselData.LastPositionUpdate.Heading != 0.0 {
-		ais.TrueHeading = &vesselData.LastPositionUpdate.Heading
+	if vesselData.LastPositionUpdate.NavigationalStatus != "" {
+		ais.NavStatus = &vesselData.LastPositionUpdate.NavigationalStatus
 	}
 
-	if vesselData.LastPositionUpdate.Course != 0.0 {
-		ais.Course = &vesselData.LastPositionUpdate.Course
+	// Spire reports the speed in knots, the UDL in km/h
+	if vesselData.LastPositionUpdate.Speed != nil {
+		speed := *vesselData.LastPositionUpdate.Speed * knotsToKmh
+		ais.Speed = &speed
 	}
 
-	if vesselData.LastPositionUpdate.NavigationalStatus != "" {
-		ais.NavStatus = &vesselData.LastPositionUpdate.NavigationalStatus
+	// both report the rate of turn in degrees per minute
+	ais.RateOfTurn = vesselData.LastPositionUpdate.ROT
+
+	switch strings.ToUpper(vesselData.LastPositionUpdate.Maneuver) {
+	case "SPECIAL MANEUVER":
+		special := true
+		ais.SpecialManeuver = &special
+	case "NO SPECIAL MANEUVER":
+		special := false
+		ais.SpecialManeuver = &special
 	}
 
 	dimensionsSlice := []float64{
@@ -193,16 +208,20 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 		ais.Draught = &vesselData.CurrentVoyage.Draught
 	}
 
+	if vesselData.CurrentVoyage.Destination != "" {
+		ais.Destination = &vesselData.CurrentVoyage.Destination
+	}
+
 	// Not every vessel has vesselData.CurrentVoyage.ETA; so those vessels will result in a DestinationETA being set to nil
 	if vesselData.CurrentVoyage.ETA != "" {
-		eta, etaErr := time.Parse(layout, vesselData.CurrentVoyage.ETA)
-		// The UDL endpoint doesn't require DestinationETA to be populated to accept a valid payload; so we set it to nil instead of erroring out
-		if etaErr != nil {
-			ais.DestinationETA = nil
+		// The UDL endpoint doesn't require DestinationETA to be populated to accept a valid payload; so we leave it nil instead of erroring out
+		if eta, etaErr := parseSpireTime(vesselData.CurrentVoyage.ETA); etaErr == nil {
+			ais.DestinationETA = &eta
 		}
-		ais.DestinationETA = &eta
 	}
 
+	// the UDL AIS model only has the LOCODE of a port, the name and center
+	// point of the matched port are not submitted
 	if vesselData.CurrentVoyage.MatchedPort.Port.Unlocode != "" {
 		ais.CurrentPortLOCODE = &vesselData.CurrentVoyage.MatchedPort.Port.Unlocode
 	}
@@ -213,30 +232,173 @@ func ToUDLAis(raw []byte, dataMode udl.AISIngestDataMode, classificationMarking
 	return ais, err
 }
 
-func ToUDLElset(raw []byte) (udl.ElsetIngest, error) {
+// toUDLAisRecord transforms a record of Spire vessel JSON or NMEA sentences
+// in the configured AIS input format.
+func toUDLAisRecord(d *Destination, r sdk.Record) ([]udl.AISIngest, error) {
+	raw := r.Payload.After.Bytes()
+	dataMode := udl.AISIngestDataMode(d.Config.DataMode)
+	format := strings.ToLower(strings.TrimSpace(d.Config.AISInputFormat))
+	if format == "" || format == aisFormatAuto {
+		format = detectAISFormat(raw)
+	}
+
+	if d.shipTypes == nil {
+		shipTypes, err := LoadShipTypeMapping(d.Config.ShipTypeMappingFile, d.Config.UnmappedShipType)
+		if err != nil {
+			return nil, err
+		}
+		d.shipTypes = shipTypes
+	}
+
+	if format == aisFormatNMEA {
+		received, err := r.Metadata.GetCreatedAt()
+		if err != nil {
+			received = time.Now().UTC()
+		}
+		return ToUDLAisNMEA(raw, d.shipTypes, received, dataMode, d.Config.ClassificationMarking, nmeaSource(d.Config))
+	}
+	return ToUDLAisSpire(raw, d.Config.SpireSchemaVersion, d.shipTypes, dataMode, d.Config.ClassificationMarking)
+}
+
+// defaultNMEASource is used when the config is parsed without the parameter
+// defaults applied.
+const defaultNMEASource = "Spire"
+
+// nmeaSource returns the configured source of AIS records decoded from NMEA.
+func nmeaSource(cfg Config) string {
+	if cfg.NMEASource == "" {
+		return defaultNMEASource
+	}
+	return cfg.NMEASource
+}
+
+// checkAis validates the configured AIS input format, Spire schema version
+// and ship type mappings.
+func checkAis(cfg Config) error {
+	switch strings.ToLower(strings.TrimSpace(cfg.AISInputFormat)) {
+	case "", aisFormatAuto, aisFormatSpire, aisFormatNMEA:
+	default:
+		return fmt.Errorf("unsupported AIS input format: %s", cfg.AISInputFormat)
+	}
+	if err := checkSpireSchema(cfg); err != nil {
+		return err
+	}
+	return checkShipTypes(cfg)
+}
+
+// ToUDLElset unmarshals an elset and sets its data mode, classification
+// marking and source. With fillMissing only the values missing from the
+// elset are set.
+func ToUDLElset(raw []byte, dataMode udl.ElsetIngestDataMode, classificationMarking, source string, fillMissing bool) (udl.ElsetIngest, error) {
 	var elset udl.ElsetIngest
-	err := json.Unmarshal(raw, &elset)
-	return elset, err
+	if err := json.Unmarshal(raw, &elset); err != nil {
+		return udl.ElsetIngest{}, err
+	}
+
+	if !fillMissing || elset.DataMode == "" {
+		elset.DataMode = dataMode
+	}
+	if !fillMissing || elset.ClassificationMarking == "" {
+		elset.ClassificationMarking = classificationMarking
+	}
+	if !fillMissing || elset.Source == "" {
+		elset.Source = source
+	}
+	return elset, nil
+}
+
+// defaultElsetSource is used when the config is parsed without the parameter
+// defaults applied.
+const defaultElsetSource = "Spire"
+
+// elsetDataMode returns the configured data mode of elsets, empty defaults
+// to TEST.
+func elsetDataMode(cfg Config) udl.ElsetIngestDataMode {
+	if cfg.DataMode == "" {
+		return udl.ElsetIngestDataModeTEST
+	}
+	return udl.ElsetIngestDataMode(cfg.DataMode)
+}
+
+// elsetSource returns the configured source of elsets.
+func elsetSource(cfg Config) string {
+	if cfg.ElsetSource == "" {
+		return defaultElsetSource
+	}
+	return cfg.ElsetSource
 }
 
-func ToUDLEphemeris(raw []byte, dataMode udl.EphemerisIngestDataMode, classificationMarking string) (UDLReport, error) {
+// ToUDLEphemeris converts an SP3 file into one UDL report per satellite.
+func ToUDLEphemeris(raw []byte, dataMode udl.EphemerisIngestDataMode, classificationMarking string, velocity VelocityOptions, norad *NoradResolver) ([]UDLReport, error) {
 	// parse raw lines to sp3 report
 	sp3Report, err := Parse(raw)
 	if err != nil {
 		sdk.Logger(context.Background()).Err(err).Msgf("error parsing decoded bytes: %s", err)
+		return nil, err
+	}
+	if len(sp3Report.Entries) == 0 {
+		return nil, errors.New("sp3 report contains no epochs")
 	}
 
-	sdk.Logger(context.Background()).Debug().Msgf("name: %s Timestamp: %s  FlightModuleNumber: %d", sp3Report.SatelliteName, sp3Report.Entries[0].Timestamp, sp3Report.Entries[0].Position.FlightModuleNumber)
+	sdk.Logger(context.Background()).Debug().Msgf("name: %s Timestamp: %s  Satellites: %v", sp3Report.SatelliteName, sp3Report.Entries[0].Timestamp, sp3Report.Header.Satellites)
 
-	// convert to UDL Report
-	//ur, err := SP3cToUDL(sp3Report)
-	ur, err := SP3cToUDL(sp3Report)
+	// convert to UDL Reports
+	reports, err := SP3ToUDL(sp3Report, velocity, norad)
 	if err != nil {
 		sdk.Logger(context.Background()).Err(err).Msgf("error converting to udl report: %s", err)
 	}
 
-	return ur, err
+	return reports, err
+
+}
+
+// ToUDLEphemerisOEM converts a CCSDS OEM into one UDL report per segment,
+// NORAD IDs are resolved through norad.
+func ToUDLEphemerisOEM(raw []byte, norad *NoradResolver) ([]UDLReport, error) {
+	oem, err := ParseOEM(raw)
+	if err != nil {
+		sdk.Logger(context.Background()).Err(err).Msgf("error parsing OEM: %s", err)
+		return nil, err
+	}
+	return OEMToUDL(oem, norad)
+}
 
+const (
+	ephemerisFormatAuto = "auto"
+	ephemerisFormatSP3  = "sp3"
+	ephemerisFormatOEM  = "oem"
+)
+
+// detectEphemerisFormat tells SP3 files, which start with a #c or #d version
+// line, from CCSDS OEM in KVN or XML format.
+func detectEphemerisFormat(raw []byte) (string, error) {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("#")):
+		return ephemerisFormatSP3, nil
+	case bytes.HasPrefix(trimmed, []byte("CCSDS_OEM_VERS")), bytes.HasPrefix(trimmed, []byte("<")):
+		return ephemerisFormatOEM, nil
+	}
+	return "", errors.New("unknown ephemeris format")
+}
+
+// toUDLEphemerides converts an ephemeris record in the configured or detected
+// input format into one UDL report per object.
+func toUDLEphemerides(raw []byte, cfg Config, norad *NoradResolver) ([]UDLReport, error) {
+	format := strings.ToLower(strings.TrimSpace(cfg.EphemerisInputFormat))
+	if format == "" || format == ephemerisFormatAuto {
+		var err error
+		if format, err = detectEphemerisFormat(raw); err != nil {
+			return nil, err
+		}
+	}
+	switch format {
+	case ephemerisFormatSP3:
+		return ToUDLEphemeris(raw, udl.EphemerisIngestDataMode(cfg.DataMode), cfg.ClassificationMarking, velocityOptions(cfg), norad)
+	case ephemerisFormatOEM:
+		return ToUDLEphemerisOEM(raw, norad)
+	}
+	return nil, fmt.Errorf("unsupported ephemeris input format: %s", format)
 }
 
 func KeyPayload(rawKey string) (string, error) {
@@ -15,10 +15,14 @@
 package destination
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
 )
 
 func TestToUDLAis(t *testing.T) {
@@ -102,11 +106,168 @@ func TestToUDLElset(t *testing.T) {
 	}`)
 
 	// Call the toUDLElset function with the test input
-	elset, err := ToUDLElset(raw)
+	elset, err := ToUDLElset(raw, udl.ElsetIngestDataModeTEST, "U", "Spire", false)
 	is.NoErr(err) // Check for no errors
 
 	// Verify the output fields
 	is.Equal(*elset.IdOnOrbit, "1")
 	expectedTimestamp, _ := time.Parse("2006-01-02T15:04:05.999Z", "2022-01-01T00:00:00.000Z")
 	is.Equal(elset.Epoch, expectedTimestamp)
+	is.Equal(elset.DataMode, udl.ElsetIngestDataModeTEST)
+	is.Equal(elset.ClassificationMarking, "U")
+	is.Equal(elset.Source, "Spire")
+}
+
+func TestToUDLElset_ConfiguredValues(t *testing.T) {
+	raw := []byte(`{
+		"idOnOrbit": "1",
+		"epoch": "2022-01-01T00:00:00.000Z",
+		"dataMode": "REAL",
+		"source": "Upstream"
+	}`)
+
+	tests := []struct {
+		name           string
+		fillMissing    bool
+		dataMode       udl.ElsetIngestDataMode
+		classification string
+		source         string
+	}{
+		{
+			name:           "override",
+			dataMode:       udl.ElsetIngestDataModeTEST,
+			classification: "U",
+			source:         "Spire",
+		},
+		{
+			name:           "fill missing",
+			fillMissing:    true,
+			dataMode:       udl.ElsetIngestDataModeREAL,
+			classification: "U",
+			source:         "Upstream",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			elset, err := ToUDLElset(raw, udl.ElsetIngestDataModeTEST, "U", "Spire", tt.fillMissing)
+			is.NoErr(err)
+			is.Equal(elset.DataMode, tt.dataMode)
+			is.Equal(elset.ClassificationMarking, tt.classification)
+			is.Equal(elset.Source, tt.source)
+		})
+	}
+}
+
+func TestVesselToUDLAis_Fields(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		vessel VesselData
+		check  func(is *is.I, ais udl.AISIngest)
+	}{
+		{
+			name:   "imo",
+			vessel: VesselData{StaticData: StaticData{IMO: 9731913}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.Imon, int64(9731913)) },
+		},
+		{
+			name:   "no imo",
+			vessel: VesselData{},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(ais.Imon, nil) },
+		},
+		{
+			name:   "destination",
+			vessel: VesselData{CurrentVoyage: CurrentVoyage{Destination: "NEW_YORK"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.Destination, "NEW YORK") },
+		},
+		{
+			name:   "eta",
+			vessel: VesselData{CurrentVoyage: CurrentVoyage{ETA: "2022-01-10T06:30:00Z"}},
+			check: func(is *is.I, ais udl.AISIngest) {
+				is.Equal(*ais.DestinationETA, time.Date(2022, 1, 10, 6, 30, 0, 0, time.UTC))
+			},
+		},
+		{
+			name:   "invalid eta",
+			vessel: VesselData{CurrentVoyage: CurrentVoyage{ETA: "01-10 06:30"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(ais.DestinationETA, nil) },
+		},
+		{
+			name:   "matched port",
+			vessel: VesselData{CurrentVoyage: CurrentVoyage{MatchedPort: MatchedPort{Port: Port{Name: "New York", Unlocode: "USNYC"}}}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.CurrentPortLOCODE, "USNYC") },
+		},
+		{
+			name:   "speed",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Speed: f(10)}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.Speed, 18.52) },
+		},
+		{
+			name:   "stationary",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Speed: f(0), ROT: f(0), Course: f(0), Heading: f(0)}},
+			check: func(is *is.I, ais udl.AISIngest) {
+				is.Equal(*ais.Speed, 0.0)
+				is.Equal(*ais.RateOfTurn, 0.0)
+				is.Equal(*ais.Course, 0.0)
+				is.Equal(*ais.TrueHeading, 0.0)
+			},
+		},
+		{
+			name:   "unknown motion",
+			vessel: VesselData{},
+			check: func(is *is.I, ais udl.AISIngest) {
+				is.Equal(ais.Speed, nil)
+				is.Equal(ais.RateOfTurn, nil)
+				is.Equal(ais.Course, nil)
+				is.Equal(ais.TrueHeading, nil)
+			},
+		},
+		{
+			name:   "rate of turn",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{ROT: f(-12.5)}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.RateOfTurn, -12.5) },
+		},
+		{
+			name:   "special maneuver",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Maneuver: "SPECIAL_MANEUVER"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.SpecialManeuver, true) },
+		},
+		{
+			name:   "no special maneuver",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Maneuver: "NO_SPECIAL_MANEUVER"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.SpecialManeuver, false) },
+		},
+		{
+			name:   "maneuver not available",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Maneuver: "NOT_AVAILABLE"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(ais.SpecialManeuver, nil) },
+		},
+		{
+			name:   "collection type",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{CollectionType: "SATELLITE"}},
+			check: func(is *is.I, ais udl.AISIngest) {
+				// the UDL AIS model has no field for the collection type
+				body, err := json.Marshal(ais)
+				is.NoErr(err)
+				is.True(!strings.Contains(string(body), "SATELLITE"))
+				is.Equal(ais.Origin, nil)
+				is.Equal(ais.OrigNetwork, nil)
+			},
+		},
+		{
+			name:   "low accuracy",
+			vessel: VesselData{LastPositionUpdate: LastPositionUpdate{Accuracy: "LOW"}},
+			check:  func(is *is.I, ais udl.AISIngest) { is.Equal(*ais.PosHiAccuracy, false) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			tt.vessel.UpdateTimestamp = "2022-01-01T00:00:00.000Z"
+			ais, err := vesselToUDLAis(tt.vessel, defaultShipTypes, udl.AISIngestDataModeTEST, "U")
+			is.NoErr(err)
+			tt.check(is, ais)
+		})
+	}
 }
@@ -21,6 +21,8 @@ import (
 	"github.com/meroxa/conduit-connector-udl-public/udl"
 
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 )
 
@@ -30,82 +32,148 @@ func OpenCDCPayload(rawPayload map[string]interface{}) string {
 	return a["opencdc.rawData"].(string)
 }
 
-func (d *Destination) writeEphemerisToUDL(ctx context.Context, records []sdk.Record) (int, error) {
-	// var ephemerisData []udl.EphemerisIngest
-	for _, r := range records {
-		ephmerisRecord, err := ToUDLEphemeris(r.Payload.After.Bytes(), udl.EphemerisIngestDataMode(d.Config.DataMode), d.Config.ClassificationMarking)
-		// sdk.Logger(ctx).Info().Msgf("ephmerisRecord: %+v", ephmerisRecord)
-		// ephemerisData = append(ephemerisData, ephmerisRecord)
-		if err != nil {
-			sdk.Logger(ctx).Err(err).Msgf("ToUDLEphemeris failed")
-			return 0, err
+// submitEphemeris uploads the report of every satellite in a record on its
+// own and returns the number of records whose reports were all accepted
+// before the first failed report. The uploads stop at the first failed
+// satellite, the satellites of the record after it are not attempted.
+func submitEphemeris(ctx context.Context, d *Destination, records [][]ephemerisUpload) (int, error) {
+	for i, uploads := range records {
+		for j, upload := range uploads {
+			if err := submitEphemerisReport(ctx, d, upload); err != nil {
+				sdk.Logger(ctx).Error().Msgf("ephemeris of satellite %d of %d failed after %d of %d records", j+1, len(uploads), i, len(records))
+				return i, fmt.Errorf("satellite %s (idOnOrbit %s): %w", upload.report.SatelliteID, upload.params.IdOnOrbit, err)
+			}
 		}
+	}
 
-		params := &udl.FiledropEphemPostIdParams{
-			IdOnOrbit:       ephmerisRecord.ID,
-			Classification:  d.Config.ClassificationMarking,
-			DataMode:        "TEST",
-			HasMnvr:         false,
-			Type:            "ROUTINE",
-			Category:        "EXTERNAL",
-			EphemFormatType: "NASA",
-			Source:          "Spire",
-		}
-		bodyReader := strings.NewReader(ephmerisRecord.String())
-		response, err := d.client.FiledropEphemPostIdWithBody(ctx, params, "applications/json", bodyReader)
-		if err != nil {
-			return 0, err
-		}
+	return len(records), nil
+}
 
-		sdk.Logger(context.Background()).Info().Msgf("Submitted Ephemeris Request Parameters - IdOnOrbit: %s, Classification: %s, DataMode: %s, HasMnvr: %t, Type: %s, Category: %s, EphemFormatType: %s, Source: %s", params.IdOnOrbit, params.Classification, params.DataMode, params.HasMnvr, params.Type, params.Category, params.EphemFormatType, params.Source)
+// submitEphemerisReport uploads the report of a single satellite.
+func submitEphemerisReport(ctx context.Context, d *Destination, upload ephemerisUpload) error {
+	params := upload.params
+	bodyReader := strings.NewReader(upload.body)
+	response, err := d.client.FiledropEphemPostIdWithBody(ctx, &params, "applications/json", bodyReader)
+	if err != nil {
+		sdk.Logger(ctx).Err(err).Msgf("FiledropEphemPostIdWithBody failed for satellite %s", upload.report.SatelliteID)
+		return err
+	}
 
-		if response.StatusCode > 300 {
-			return 0, fmt.Errorf(fmt.Sprintf("unsuccessful status code returned %d; response: %+v", response.StatusCode, response.Body))
-		}
+	sdk.Logger(context.Background()).Info().Msgf("Submitted Ephemeris Request Parameters - IdOnOrbit: %s, Classification: %s, DataMode: %s, HasMnvr: %t, Type: %s, Category: %s, EphemFormatType: %s, Source: %s", params.IdOnOrbit, params.Classification, params.DataMode, params.HasMnvr, params.Type, params.Category, params.EphemFormatType, params.Source)
 
-		sdk.Logger(context.Background()).Info().Msgf("Spire to Ephemeris UDL response: %+v:", response)
+	if response.StatusCode >= 300 {
+		sdk.Logger(ctx).Error().Msgf("FiledropEphemPostIdWithBody failed with status code %v for satellite %s", response.StatusCode, upload.report.SatelliteID)
+		return responseError(response)
 	}
 
-	return 1, nil
+	sdk.Logger(context.Background()).Info().Msgf("Spire to Ephemeris UDL response for satellite %s: %+v:", upload.report.SatelliteID, response)
+	return nil
 }
 
-func (d *Destination) writeAisToUDL(ctx context.Context, records []sdk.Record) (int, error) {
+// submitAis submits the AIS messages of every record and returns the number
+// of records whose messages were all accepted.
+func submitAis(ctx context.Context, d *Destination, records [][]udl.AISIngest) (int, error) {
 	var aisData []udl.AISIngest
-	for _, r := range records {
-		ais, err := ToUDLAis(r.Payload.After.Bytes(), udl.AISIngestDataMode(d.Config.DataMode), d.Config.ClassificationMarking)
-		aisData = append(aisData, ais)
-		if err != nil {
-			sdk.Logger(ctx).Err(err).Msgf("ToUDLAis failed")
-			return 0, err
-		}
+	for _, ais := range records {
+		aisData = append(aisData, ais...)
 	}
+	written, err := submitChunked(ctx, d, "FiledropUdlAisPostId", aisData, jsonArray[udl.AISIngest](),
+		func(ctx context.Context, chunk []udl.AISIngest) (*http.Response, error) {
+			resp, err := d.client.FiledropUdlAisPostId(ctx, chunk)
+			if err == nil && resp.StatusCode < 300 {
+				sdk.Logger(ctx).Info().Msgf("Spire to AIS UDL response: %+v", resp)
+			}
+			return resp, err
+		})
+	return recordsWritten(records, written), err
+}
 
-	resp, err := d.client.FiledropUdlAisPostId(ctx, aisData)
-	if err != nil || resp.StatusCode >= 300 {
-		sdk.Logger(ctx).Err(err).Msgf("FiledropUdlAisPostId failed with status code: %v", resp.StatusCode)
-		return 0, err
+func submitElsets(ctx context.Context, d *Destination, elsets []udl.ElsetIngest) (int, error) {
+	return submitChunked(ctx, d, "FiledropUdlElsetPostId", elsets, jsonArray[udl.ElsetIngest](),
+		func(ctx context.Context, chunk []udl.ElsetIngest) (*http.Response, error) {
+			return d.client.FiledropUdlElsetPostId(ctx, chunk)
+		})
+}
+
+// submitTLEs creates elsets from the TLEs of every record, either through
+// createBulkFromTLE or as parsed elsets through the elset filedrop, and
+// returns the number of records whose TLEs were all accepted.
+func submitTLEs(ctx context.Context, d *Destination, records [][]TLE) (int, error) {
+	var (
+		written int
+		err     error
+	)
+	if tleSubmitMethod(d.Config) == tleSubmitFiledrop {
+		written, err = submitElsets(ctx, d, tleElsets(d.Config, records))
+	} else {
+		written, err = submitBulkTLEs(ctx, d, records)
 	}
-	sdk.Logger(ctx).Info().Msgf("Spire to AIS UDL response: %+v", resp)
 
-	return len(aisData), nil
+	return recordsWritten(records, written), err
 }
 
-func (d *Destination) writeElsetToUDL(ctx context.Context, records []sdk.Record) (int, error) {
-	var elsets []udl.ElsetIngest
-	for _, r := range records {
-		elset, err := ToUDLElset(r.Payload.After.Bytes())
-		if err != nil {
-			sdk.Logger(ctx).Err(err).Msgf("ToUDLElset failed")
-			return 0, err
+// recordsWritten returns the number of records whose items were all written
+// when the first written items of the flattened records were accepted.
+func recordsWritten[T any](records [][]T, written int) int {
+	for i, items := range records {
+		if written < len(items) {
+			return i
 		}
-		elsets = append(elsets, elset)
+		written -= len(items)
 	}
+	return len(records)
+}
+
+// submitBulkTLEs posts the TLEs to createBulkFromTLE and returns the number
+// of TLEs accepted.
+func submitBulkTLEs(ctx context.Context, d *Destination, records [][]TLE) (int, error) {
+	var lines []string
+	for _, tles := range records {
+		for _, tle := range tles {
+			lines = append(lines, tle.String())
+		}
+	}
+
+	params := tleParams(d.Config)
+	return submitChunked(ctx, d, "CreateBulkFromTLE", lines, plainText,
+		func(ctx context.Context, chunk []string) (*http.Response, error) {
+			return d.client.CreateBulkFromTLEWithBody(ctx, &params, "text/plain", strings.NewReader(strings.Join(chunk, "")))
+		})
+}
 
-	resp, err := d.client.FiledropUdlElsetPostId(ctx, elsets)
-	if err != nil || resp.StatusCode > 300 {
-		sdk.Logger(ctx).Err(err).Msgf("FiledropUdlElsetPostId failed with status code: %v", resp.StatusCode)
+// submitChunked splits the batch into chunks within the configured request
+// limits, measured in format, and posts them one after another. It returns the number of items
+// accepted up to the first failed chunk. An empty batch is not posted, the
+// UDL rejects a null body.
+func submitChunked[T any](ctx context.Context, d *Destination, name string, batch []T, format bodyFormat[T], post func(context.Context, []T) (*http.Response, error)) (int, error) {
+	chunks, err := chunk(batch, d.Config.MaxRecordsPerRequest, d.Config.MaxBytesPerRequest, format)
+	if err != nil {
 		return 0, err
 	}
 
-	return len(elsets), nil
+	written := 0
+	for i, c := range chunks {
+		resp, err := post(ctx, c)
+		if err != nil {
+			sdk.Logger(ctx).Err(err).Msgf("%s failed on chunk %d of %d", name, i+1, len(chunks))
+			return written, err
+		}
+		if resp.StatusCode >= 300 {
+			sdk.Logger(ctx).Error().Msgf("%s failed with status code %v on chunk %d of %d", name, resp.StatusCode, i+1, len(chunks))
+			return written, responseError(resp)
+		}
+		written += len(c)
+	}
+	return written, nil
+}
+
+// responseError describes an unsuccessful UDL response including its body,
+// which holds the validation errors reported by the UDL.
+func responseError(resp *http.Response) error {
+	if resp.Body == nil {
+		return fmt.Errorf("unsuccessful status code returned %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return fmt.Errorf("unsuccessful status code returned %d; response: %s", resp.StatusCode, body)
 }
@@ -0,0 +1,237 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+func ephemerisRecords(n int) []sdk.Record {
+	records := make([]sdk.Record, n)
+	for i := range records {
+		records[i] = sdk.Record{Payload: sdk.Change{After: sdk.RawData(sampleFile())}}
+	}
+	return records
+}
+
+func TestWriteEphemeris_PartialFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fail mockFailure
+		want int
+	}{
+		{
+			name: "transport error mid-batch",
+			fail: mockFailure{at: 2, err: errors.New("connection reset by peer")},
+			want: 2,
+		},
+		{
+			name: "status error mid-batch",
+			fail: mockFailure{at: 2, status: http.StatusBadRequest, body: `{"message": "invalid ephemeris"}`},
+			want: 2,
+		},
+		{
+			name: "first upload fails",
+			fail: mockFailure{status: http.StatusInternalServerError},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			client := &mockClient{fail: map[string]mockFailure{mockEphem: tt.fail}}
+			dest := Destination{client: client, norad: defaultNoradResolver}
+			dest.Config.DataType = "EPHEMERIS"
+			dest.Config.ClassificationMarking = "U"
+
+			n, err := dest.Write(context.Background(), ephemerisRecords(10))
+			is.True(err != nil)
+			is.Equal(n, tt.want) // only the accepted uploads are acknowledged
+			is.Equal(len(client.ephemIDs), tt.want)
+		})
+	}
+}
+
+func TestWriteEphemeris_AllAccepted(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.ClassificationMarking = "U"
+
+	n, err := dest.Write(context.Background(), ephemerisRecords(3))
+	is.NoErr(err)
+	is.Equal(n, 3)
+	is.Equal(client.ephemIDs, []string{"48925", "48925", "48925"})
+}
+
+func TestWriteEphemeris_MultipleSatellites(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	records := []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleFileSP3d())}}}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(client.ephemIDs, []string{"48925", "46502"}) // one upload per satellite
+}
+
+func TestWriteEphemeris_SatelliteFailure(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{mockEphem: {at: 1, status: http.StatusBadRequest}}}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	records := []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleFileSP3d())}}}
+
+	n, err := dest.Write(context.Background(), records)
+	is.Equal(n, 0) // the record is only acknowledged when every satellite was accepted
+	is.Equal(client.ephemIDs, []string{"48925"})
+	is.True(strings.Contains(err.Error(), "satellite 144 (idOnOrbit 46502)"))
+}
+
+func TestWriteEphemeris_StopsAtFailedSatellite(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{mockEphem: {status: http.StatusServiceUnavailable}}}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	records := []sdk.Record{{Payload: sdk.Change{After: sdk.RawData(sampleFileSP3d())}}}
+
+	n, err := dest.Write(context.Background(), records)
+	is.Equal(n, 0)
+	is.True(strings.Contains(err.Error(), "idOnOrbit 48925"))
+	is.True(!strings.Contains(err.Error(), "46502")) // the second satellite is not attempted
+}
+
+func TestWriteEphemeris_OEMOutput(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.EphemerisFormatType = "OEM"
+	records := ephemerisRecords(1)
+	records = append(records, records[0])
+	records[1].Metadata = sdk.Metadata{MetadataEphemerisFormatType: "NASA"}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	is.Equal(client.ephemParams[0].EphemFormatType, udl.OEM)
+	is.True(strings.HasPrefix(client.ephemBodies[0], "CCSDS_OEM_VERS = 2.0\n"))
+	is.True(strings.Contains(client.ephemBodies[0], "OBJECT_NAME = LEMUR-2-JOHN-TREIRES\nOBJECT_ID = 48925\n"))
+	is.True(strings.Contains(client.ephemBodies[0], "REF_FRAME = ITRF\nTIME_SYSTEM = GPS\n"))
+	is.True(strings.Contains(client.ephemBodies[0], "\n2022-07-06T01:18:13.000000 -6658.162753 -1527.302901 -971.376727 0.6844820031 1.7028031395 -7.4566102286\n"))
+
+	// the format overridden by metadata gets the text format
+	is.Equal(client.ephemParams[1].EphemFormatType, udl.NASA)
+	is.True(strings.HasPrefix(client.ephemBodies[1], "22187011813.000 "))
+}
+
+func TestWriteEphemeris_Params(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	dest.Config.DataMode = "REAL"
+	dest.Config.ClassificationMarking = "U"
+	dest.Config.EphemerisType = "LAUNCH"
+	dest.Config.EphemerisCategory = "OWNER_OPERATOR"
+	dest.Config.EphemerisFormatType = "modITC"
+	dest.Config.EphemerisSource = "Operator"
+	dest.Config.EphemerisOrigin = "Spire"
+	records := ephemerisRecords(2)
+	records[1].Metadata = sdk.Metadata{
+		MetadataEphemerisDataMode:    "exercise",
+		MetadataEphemerisType:        "MNVR_PLAN",
+		MetadataEphemerisHasManeuver: "true",
+		MetadataEphemerisOrigin:      "Analyst",
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 2)
+
+	origin := "Spire"
+	is.Equal(client.ephemParams[0], udl.FiledropEphemPostIdParams{
+		IdOnOrbit:       "48925",
+		Classification:  "U",
+		DataMode:        udl.DataModeREAL,
+		Type:            "LAUNCH",
+		Category:        "OWNER_OPERATOR",
+		EphemFormatType: udl.ModITC,
+		Origin:          &origin,
+		Source:          "Operator",
+	})
+	origin = "Analyst"
+	is.Equal(client.ephemParams[1], udl.FiledropEphemPostIdParams{
+		IdOnOrbit:       "48925",
+		Classification:  "U",
+		DataMode:        udl.DataModeEXERCISE,
+		HasMnvr:         true,
+		Type:            "MNVR_PLAN",
+		Category:        "OWNER_OPERATOR",
+		EphemFormatType: udl.ModITC,
+		Origin:          &origin,
+		Source:          "Operator",
+	})
+}
+
+func TestWriteEphemeris_InvalidParamOverride(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client, norad: defaultNoradResolver}
+	dest.Config.DataType = "EPHEMERIS"
+	records := ephemerisRecords(2)
+	records[1].Metadata = sdk.Metadata{MetadataEphemerisFormatType: "CSV"}
+
+	n, err := dest.Write(context.Background(), records)
+	is.True(err != nil)
+	is.Equal(n, 1) // the record in front of the invalid one is written
+	is.Equal(len(client.ephemIDs), 1)
+}
+
+func TestConfigure_UnsupportedEphemerisFormatType(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "EPHEMERIS",
+		"dataMode":              "REAL",
+		"ephemerisFormatType":   "CSV",
+	})
+	is.True(err != nil)
+}
+
+func TestResponseError(t *testing.T) {
+	is := is.New(t)
+
+	err := responseError(&http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       io.NopCloser(strings.NewReader(`{"message": "invalid ephemeris"}`)),
+	})
+	is.Equal(err.Error(), `unsuccessful status code returned 400; response: {"message": "invalid ephemeris"}`)
+}
@@ -7,7 +7,8 @@ import (
 
 var DataModeValues = []string{"TEST", "REAL", "SIMULATED", "EXERCISE"}
 
-var DataTypeValues = []string{"AIS", "ELSET", "EPHEMERIS"}
+// DataTypeValues are the data types a writer is registered for.
+var DataTypeValues = dataTypeValues()
 
 func SupportedStringValues(check string, supported []string) bool {
 	for _, ds := range supported {
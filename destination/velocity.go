@@ -0,0 +1,174 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	velocityLagrange   = "lagrange"
+	velocityDifference = "difference"
+	velocityNone       = "none"
+
+	defaultVelocityPoints = 9
+)
+
+// VelocityOptions configure how velocities are derived for SP3 files without
+// velocity records.
+type VelocityOptions struct {
+	// Method is lagrange, difference or none, empty defaults to lagrange.
+	Method string
+	// Points is the number of positions a Lagrange polynomial is fitted
+	// through, 0 defaults to 9.
+	Points int
+}
+
+// velocityOptions returns the velocity options of the config.
+func velocityOptions(cfg Config) VelocityOptions {
+	return VelocityOptions{
+		Method: strings.ToLower(strings.TrimSpace(cfg.VelocityMethod)),
+		Points: cfg.VelocityPoints,
+	}
+}
+
+func (o VelocityOptions) withDefaults() VelocityOptions {
+	if o.Method == "" {
+		o.Method = velocityLagrange
+	}
+	if o.Points == 0 {
+		o.Points = defaultVelocityPoints
+	}
+	return o
+}
+
+func (o VelocityOptions) validate() error {
+	o = o.withDefaults()
+	switch o.Method {
+	case velocityLagrange, velocityDifference, velocityNone:
+	default:
+		return fmt.Errorf("unsupported velocity method: %s", o.Method)
+	}
+	if o.Points < 2 {
+		return fmt.Errorf("velocity points must be at least 2, got %d", o.Points)
+	}
+	return nil
+}
+
+// deriveVelocities differentiates the positions of a single satellite and
+// returns the velocity in km/s at the epoch of every entry.
+func deriveVelocities(entries []Entry, opts VelocityOptions) ([][3]float64, error) {
+	opts = opts.withDefaults()
+	if opts.Method == velocityNone {
+		return nil, errors.New("missing velocity records and velocity derivation is disabled")
+	}
+	if len(entries) < 2 {
+		return nil, errors.New("at least 2 positions are needed to derive velocities")
+	}
+
+	// times are seconds since the first epoch, positions are in km
+	t := make([]float64, len(entries))
+	pos := make([][3]float64, len(entries))
+	for i, e := range entries {
+		t[i] = e.Timestamp.Sub(entries[0].Timestamp).Seconds()
+		if i > 0 && t[i] <= t[i-1] {
+			return nil, fmt.Errorf("epochs are not increasing at %s", e.Timestamp)
+		}
+		for k, v := range []string{e.Position.X, e.Position.Y, e.Position.Z} {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, err
+			}
+			pos[i][k] = f
+		}
+	}
+
+	points := opts.Points
+	if points > len(entries) {
+		points = len(entries)
+	}
+
+	vel := make([][3]float64, len(entries))
+	for i := range entries {
+		if opts.Method == velocityDifference {
+			// central difference, one-sided at the ends of the series
+			lo, hi := i-1, i+1
+			if lo < 0 {
+				lo = 0
+			}
+			if hi > len(entries)-1 {
+				hi = len(entries) - 1
+			}
+			for k := 0; k < 3; k++ {
+				vel[i][k] = (pos[hi][k] - pos[lo][k]) / (t[hi] - t[lo])
+			}
+			continue
+		}
+
+		from, to := window(i, points, len(entries))
+		for k := 0; k < 3; k++ {
+			vel[i][k] = lagrangeDerivative(t[from:to], column(pos[from:to], k), t[i])
+		}
+	}
+	return vel, nil
+}
+
+// window returns the range of n points around i within a series of length.
+func window(i, n, length int) (int, int) {
+	from := i - n/2
+	if from < 0 {
+		from = 0
+	}
+	if from+n > length {
+		from = length - n
+	}
+	return from, from + n
+}
+
+func column(pos [][3]float64, k int) []float64 {
+	out := make([]float64, len(pos))
+	for i, p := range pos {
+		out[i] = p[k]
+	}
+	return out
+}
+
+// lagrangeDerivative returns the derivative at x of the Lagrange interpolating
+// polynomial through the points (xs[j], ys[j]).
+func lagrangeDerivative(xs, ys []float64, x float64) float64 {
+	var sum float64
+	for j := range xs {
+		// derivative of the basis polynomial l_j at x
+		var dl float64
+		for i := range xs {
+			if i == j {
+				continue
+			}
+			term := 1 / (xs[j] - xs[i])
+			for m := range xs {
+				if m == i || m == j {
+					continue
+				}
+				term *= (x - xs[m]) / (xs[j] - xs[m])
+			}
+			dl += term
+		}
+		sum += ys[j] * dl
+	}
+	return sum
+}
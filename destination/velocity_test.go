@@ -0,0 +1,119 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// positionOnly removes the velocity records from an SP3 file.
+func positionOnly(raw []byte) []byte {
+	var out [][]byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("V")) {
+			out = append(out, line)
+		}
+	}
+	out[0][2] = 'P'
+	return bytes.Join(out, []byte("\n"))
+}
+
+// cubicEntries returns entries with x = t^3 km at t = 0, 10, 20, ... seconds.
+func cubicEntries(n int) []Entry {
+	start := time.Date(2022, 7, 6, 1, 18, 13, 0, time.UTC)
+	entries := make([]Entry, n)
+	for i := range entries {
+		t := float64(i * 10)
+		entries[i] = Entry{
+			Timestamp: start.Add(time.Duration(i*10) * time.Second),
+			Position: Position{
+				SatelliteID: "143",
+				X:           strconv.FormatFloat(t*t*t, 'f', 6, 64),
+				Y:           strconv.FormatFloat(2*t, 'f', 6, 64),
+				Z:           "0",
+			},
+		}
+	}
+	return entries
+}
+
+func TestDeriveVelocities_Lagrange(t *testing.T) {
+	is := is.New(t)
+
+	vel, err := deriveVelocities(cubicEntries(10), VelocityOptions{Method: "lagrange", Points: 4})
+	is.NoErr(err)
+	for i, v := range vel {
+		tt := float64(i * 10)
+		is.True(math.Abs(v[0]-3*tt*tt) < 1e-6) // a cubic is reproduced exactly by 4 points, also at the ends
+		is.True(math.Abs(v[1]-2) < 1e-9)
+		is.Equal(v[2], 0.0)
+	}
+}
+
+func TestDeriveVelocities_Difference(t *testing.T) {
+	is := is.New(t)
+
+	vel, err := deriveVelocities(cubicEntries(3), VelocityOptions{Method: "difference"})
+	is.NoErr(err)
+	is.Equal(vel[0][0], 100.0) // (1000 - 0) / 10
+	is.Equal(vel[1][0], 400.0) // (8000 - 0) / 20
+	is.Equal(vel[2][0], 700.0) // (8000 - 1000) / 10
+	is.Equal(vel[1][1], 2.0)
+}
+
+func TestDeriveVelocities_None(t *testing.T) {
+	is := is.New(t)
+
+	_, err := deriveVelocities(cubicEntries(3), VelocityOptions{Method: "none"})
+	is.True(err != nil)
+}
+
+func TestDeriveVelocities_SinglePosition(t *testing.T) {
+	is := is.New(t)
+
+	_, err := deriveVelocities(cubicEntries(1), VelocityOptions{})
+	is.True(err != nil)
+}
+
+func TestSP3ToUDL_PositionOnly(t *testing.T) {
+	is := is.New(t)
+
+	report, err := Parse(positionOnly(sampleFileSP3d()))
+	is.NoErr(err)
+	is.Equal(report.Header.PosVelFlag, byte('P'))
+	is.Equal(len(report.Entries), 4)
+
+	reports, err := SP3ToUDL(report, VelocityOptions{}, defaultNoradResolver)
+	is.NoErr(err)
+	is.Equal(len(reports), 2)
+	// two epochs one second apart, so the velocity is the position difference
+	is.Equal(reports[0].Entries[0].Velocity.X, "0.6886340000")
+	is.Equal(reports[0].Entries[1].Velocity.X, "0.6886340000")
+}
+
+func TestVelocityOptions_Validate(t *testing.T) {
+	is := is.New(t)
+
+	is.NoErr(VelocityOptions{}.validate())
+	is.NoErr(VelocityOptions{Method: "difference"}.validate())
+	is.True(VelocityOptions{Method: "spline"}.validate() != nil)
+	is.True(VelocityOptions{Points: 1}.validate() != nil)
+}
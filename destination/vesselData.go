@@ -15,12 +15,12 @@
 package destination
 
 type Dimensions struct {
-	A      float64 `json:"a, omitempty"`
-	B      float64 `json:"b, omitempty"`
-	C      float64 `json:"c, omitempty"`
-	D      float64 `json:"d, omitempty"`
-	Length float64 `json:"length, omitempty"`
-	Width  float64 `json:"width, omitempty"`
+	A      float64 `json:"a,omitempty"`
+	B      float64 `json:"b,omitempty"`
+	C      float64 `json:"c,omitempty"`
+	D      float64 `json:"d,omitempty"`
+	Length float64 `json:"length,omitempty"`
+	Width  float64 `json:"width,omitempty"`
 }
 
 type StaticData struct {
@@ -38,18 +38,18 @@ type StaticData struct {
 }
 
 type LastPositionUpdate struct {
-	Accuracy           string  `json:"accuracy"`
-	CollectionType     string  `json:"collectionType"`
-	Course             float64 `json:"course"`
-	Heading            float64 `json:"heading"`
-	Latitude           float64 `json:"latitude"`
-	Longitude          float64 `json:"longitude"`
-	Maneuver           string  `json:"maneuver"`
-	NavigationalStatus string  `json:"navigationalStatus"`
-	ROT                float64 `json:"rot"`
-	Speed              float64 `json:"speed, omitempty"`
-	Timestamp          string  `json:"timestamp"`
-	UpdateTimestamp    string  `json:"updateTimestamp"`
+	Accuracy           string   `json:"accuracy"`
+	CollectionType     string   `json:"collectionType"`
+	Course             *float64 `json:"course"`
+	Heading            *float64 `json:"heading"`
+	Latitude           float64  `json:"latitude"`
+	Longitude          float64  `json:"longitude"`
+	Maneuver           string   `json:"maneuver"`
+	NavigationalStatus string   `json:"navigationalStatus"`
+	ROT                *float64 `json:"rot"`
+	Speed              *float64 `json:"speed,omitempty"`
+	Timestamp          string   `json:"timestamp"`
+	UpdateTimestamp    string   `json:"updateTimestamp"`
 }
 
 type GeoPoint struct {
@@ -0,0 +1,238 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// writer transforms records of a single data type and submits them to the UDL.
+type writer interface {
+	// validate checks the config settings the data type depends on.
+	validate(cfg Config) error
+	// write submits the records and returns the error of every record, nil
+	// for a record that was written or skipped.
+	write(ctx context.Context, d *Destination, records []sdk.Record) []error
+}
+
+// batchWriter is a writer that transforms every record into the UDL ingest
+// model T and submits the transformed batch to the endpoint of the data type.
+type batchWriter[T any] struct {
+	// name is used in log and error messages.
+	name string
+	// transform converts a record into the ingest model. It gets the
+	// destination for its config and the state kept across records.
+	transform func(d *Destination, r sdk.Record) (T, error)
+	// submit sends the batch to the UDL and returns how many were accepted.
+	submit func(ctx context.Context, d *Destination, batch []T) (int, error)
+	// check validates data type specific config settings, it may be nil.
+	check func(cfg Config) error
+}
+
+func (w batchWriter[T]) validate(cfg Config) error {
+	if !SupportedStringValues(cfg.DataMode, DataModeValues) {
+		return fmt.Errorf("unsupported data mode: %s", cfg.DataMode)
+	}
+	if w.check == nil {
+		return nil
+	}
+	return w.check(cfg)
+}
+
+func (w batchWriter[T]) write(ctx context.Context, d *Destination, records []sdk.Record) []error {
+	policy := transformErrorPolicy(d.Config.TransformErrorPolicy)
+	errs := make([]error, len(records))
+	batch := make([]T, 0, len(records))
+	// indices holds the position in records of every transformed record
+	indices := make([]int, 0, len(records))
+	for i, r := range records {
+		v, err := w.transform(d, r)
+		if err == nil {
+			batch = append(batch, v)
+			indices = append(indices, i)
+			continue
+		}
+
+		var lookupErr *LookupError
+		if errors.As(err, &lookupErr) {
+			// the record is not at fault, so it fails the write to be
+			// retried whatever the policy is
+			sdk.Logger(ctx).Err(err).Msgf("record %d: %s failed", i, w.name)
+			errs[i] = err
+			notWritten(errs[i+1:])
+			break
+		}
+
+		err = &TransformError{Writer: w.name, Position: r.Position, Err: err}
+		sdk.Logger(ctx).Err(err).Str("policy", policy).Msgf("record %d: %s transform failed", i, w.name)
+		if policy == policySkip {
+			continue
+		}
+		// the bad record is the end of the batch, for the dlq policy as well:
+		// records after it are redelivered, submitting them now would post
+		// them twice
+		errs[i] = err
+		notWritten(errs[i+1:])
+		break
+	}
+
+	if len(batch) == 0 {
+		return errs
+	}
+	n, err := w.submit(ctx, d, batch)
+	for _, i := range indices[n:] {
+		errs[i] = err
+	}
+	return errs
+}
+
+// errNotWritten is the error of a record left unwritten because of the error
+// of a record in front of it.
+var errNotWritten = errors.New("record not written after an earlier record failed")
+
+// notWritten sets the errors of records following a failed one.
+func notWritten(errs []error) {
+	for i := range errs {
+		errs[i] = errNotWritten
+	}
+}
+
+const (
+	policyFail = "fail"
+	policySkip = "skip"
+	policyDLQ  = "dlq"
+)
+
+// TransformErrorPolicyValues are the accepted transformErrorPolicy values.
+var TransformErrorPolicyValues = []string{policyFail, policySkip, policyDLQ}
+
+// transformErrorPolicy returns the canonical policy, an empty policy fails.
+func transformErrorPolicy(policy string) string {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if policy == "" {
+		return policyFail
+	}
+	return policy
+}
+
+// validTransformErrorPolicy reports whether the policy is supported.
+func validTransformErrorPolicy(policy string) bool {
+	for _, p := range TransformErrorPolicyValues {
+		if transformErrorPolicy(policy) == p {
+			return true
+		}
+	}
+	return false
+}
+
+// TransformError is returned for a record that could not be transformed into
+// the UDL model. It names the record by its position only, payloads can be
+// large or classified and the dead-letter queue receives the record anyway.
+type TransformError struct {
+	Writer   string
+	Position sdk.Position
+	Err      error
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("%s transform failed for record at position %s: %v", e.Writer, e.Position, e.Err)
+}
+
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// writers maps the canonical data type to the writer submitting it. The
+// dataType validation in config.Config has to list exactly these keys.
+var writers = map[string]writer{
+	"AIS": batchWriter[[]udl.AISIngest]{
+		name: "ToUDLAis",
+		transform: func(d *Destination, r sdk.Record) ([]udl.AISIngest, error) {
+			return toUDLAisRecord(d, r)
+		},
+		submit: submitAis,
+		check:  checkAis,
+	},
+	"ELSET": batchWriter[udl.ElsetIngest]{
+		name: "ToUDLElset",
+		transform: func(d *Destination, r sdk.Record) (udl.ElsetIngest, error) {
+			cfg := d.Config
+			return ToUDLElset(r.Payload.After.Bytes(), elsetDataMode(cfg), cfg.ClassificationMarking, elsetSource(cfg), cfg.ElsetFillMissing)
+		},
+		submit: submitElsets,
+	},
+	"EPHEMERIS": batchWriter[[]ephemerisUpload]{
+		name: "ToUDLEphemeris",
+		transform: func(d *Destination, r sdk.Record) ([]ephemerisUpload, error) {
+			cfg := d.Config
+			reports, err := toUDLEphemerides(r.Payload.After.Bytes(), cfg, d.norad)
+			if err != nil {
+				return nil, err
+			}
+			uploads := make([]ephemerisUpload, len(reports))
+			for i, report := range reports {
+				params, err := ephemerisParams(report.ID, cfg, r.Metadata)
+				if err != nil {
+					return nil, err
+				}
+				if uploads[i], err = newEphemerisUpload(report, params, time.Now()); err != nil {
+					return nil, err
+				}
+			}
+			return uploads, nil
+		},
+		submit: submitEphemeris,
+		check:  checkEphemeris,
+	},
+	"TLE": batchWriter[[]TLE]{
+		name: "ParseTLEs",
+		transform: func(_ *Destination, r sdk.Record) ([]TLE, error) {
+			return ParseTLEs(r.Payload.After.Bytes())
+		},
+		submit: submitTLEs,
+		check:  checkTLE,
+	},
+}
+
+// canonicalDataType returns the writers key for a configured data type.
+func canonicalDataType(dataType string) string {
+	return strings.ToUpper(strings.TrimSpace(dataType))
+}
+
+// writerFor returns the writer for the data type.
+func writerFor(dataType string) (writer, error) {
+	w, ok := writers[canonicalDataType(dataType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported data type: %s", dataType)
+	}
+	return w, nil
+}
+
+func dataTypeValues() []string {
+	values := make([]string, 0, len(writers))
+	for k := range writers {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	return values
+}
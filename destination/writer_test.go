@@ -0,0 +1,174 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destination
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+func TestWriters_MatchConfigValidation(t *testing.T) {
+	is := is.New(t)
+
+	var inclusion []string
+	for _, v := range (Config{}).Parameters()["dataType"].Validations {
+		if in, ok := v.(sdk.ValidationInclusion); ok {
+			inclusion = in.List
+		}
+	}
+	sort.Strings(inclusion)
+	is.Equal(inclusion, DataTypeValues) // every accepted dataType has a writer and vice versa
+}
+
+func TestConfigure_CanonicalDataType(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "Elset",
+		"dataMode":              "TEST",
+	})
+	is.NoErr(err)
+	is.Equal(dest.Config.DataType, "ELSET")
+}
+
+func TestConfigure_UnsupportedDataType(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIRCRAFT",
+		"dataMode":              "TEST",
+	})
+	is.True(err != nil)
+}
+
+func TestWrite_Elset(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "ELSET"
+	records := []sdk.Record{
+		{Payload: sdk.Change{After: sdk.RawData(`{"idOnOrbit": "1", "epoch": "2022-01-01T00:00:00.000Z"}`)}},
+		{Payload: sdk.Change{After: sdk.RawData(`{"idOnOrbit": "2", "epoch": "2022-01-01T00:00:00.000Z"}`)}},
+	}
+
+	n, err := dest.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	is.Equal(len(client.elsets), 2)
+	is.Equal(*client.elsets[1].IdOnOrbit, "2")
+	is.Equal(client.elsets[1].DataMode, udl.ElsetIngestDataModeTEST)
+	is.Equal(client.elsets[1].Source, "Spire")
+}
+
+func badRecord() sdk.Record {
+	return sdk.Record{
+		Position: sdk.Position("bad-1"),
+		Metadata: sdk.Metadata{},
+		Payload:  sdk.Change{After: sdk.RawData(`{"id": "2", "updateTimestamp": `)},
+	}
+}
+
+func TestWrite_TransformErrorPolicy(t *testing.T) {
+	testCases := []struct {
+		policy  string
+		wantN   int
+		wantAis int
+	}{
+		{policy: "", wantN: 1, wantAis: 1},
+		{policy: "fail", wantN: 1, wantAis: 1},
+		{policy: "skip", wantN: 3, wantAis: 2},
+		{policy: "dlq", wantN: 1, wantAis: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			is := is.New(t)
+			client := &mockClient{}
+			dest := Destination{client: client}
+			dest.Config.DataType = "AIS"
+			dest.Config.DataMode = "TEST"
+			dest.Config.TransformErrorPolicy = tc.policy
+
+			n, err := dest.Write(context.Background(), []sdk.Record{aisRecord(), badRecord(), aisRecord()})
+			is.Equal(n, tc.wantN)
+			is.Equal(len(client.ais), tc.wantAis)
+			if tc.policy == "skip" {
+				is.NoErr(err)
+				return
+			}
+			var terr *TransformError
+			is.True(errors.As(err, &terr))
+			is.Equal(terr.Position, sdk.Position("bad-1"))
+			is.True(strings.Contains(err.Error(), "position bad-1"))
+			is.True(!strings.Contains(err.Error(), `"id": "2"`)) // the payload is not part of the error
+		})
+	}
+}
+
+func TestWrite_SkipTransformErrorAfterFailedSubmit(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{fail: map[string]mockFailure{mockElset: {status: http.StatusBadRequest}}}
+	dest := Destination{client: client}
+	dest.Config.DataType = "ELSET"
+	dest.Config.TransformErrorPolicy = "skip"
+	bad := elsetRecord()
+	bad.Payload.After = sdk.RawData(`[]`)
+
+	n, err := dest.Write(context.Background(), []sdk.Record{bad, elsetRecord()})
+	is.True(err != nil)
+	is.Equal(n, 1) // the skipped record in front of the failed one is done
+}
+
+func TestWrite_DLQMixedDataTypes(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{}
+	dest := Destination{client: client}
+	dest.Config.DataType = "AIS"
+	dest.Config.DataMode = "TEST"
+	dest.Config.TransformErrorPolicy = "dlq"
+
+	n, err := dest.Write(context.Background(), []sdk.Record{aisRecord(), elsetRecord(), badRecord(), aisRecord()})
+	is.Equal(n, 2) // the bad record is nacked, not the elset in front of it
+	var terr *TransformError
+	is.True(errors.As(err, &terr))
+	is.Equal(terr.Position, sdk.Position("bad-1"))
+	is.Equal(len(client.ais), 1) // the good record after the bad one is left for redelivery
+	is.Equal(len(client.elsets), 1)
+}
+
+func TestConfigure_UnsupportedTransformErrorPolicy(t *testing.T) {
+	is := is.New(t)
+	dest := Destination{}
+	err := dest.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"dataMode":              "TEST",
+		"transformErrorPolicy":  "retry",
+	})
+	is.True(err != nil)
+}
@@ -1,11 +1,12 @@
 module github.com/meroxa/conduit-connector-udl-public
 
-go 1.18
+go 1.21
 
 require (
 	github.com/conduitio/conduit-connector-sdk v0.7.2
 	github.com/deepmap/oapi-codegen v1.12.4
 	github.com/matryer/is v1.4.1
+	golang.org/x/time v0.3.0
 )
 
 require (
@@ -41,7 +42,6 @@ require (
 	golang.org/x/net v0.9.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
-	golang.org/x/time v0.3.0 // indirect
 	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
 	google.golang.org/grpc v1.54.0 // indirect
 	google.golang.org/protobuf v1.30.0 // indirect
@@ -0,0 +1,50 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:generate paramgen -output=paramgen_src.go Config
+
+package source
+
+import "time"
+
+type Config struct {
+	// The HTTP Basic Auth Username to use when accessing the UDL.
+	HTTPBasicAuthUsername string `validate:"required"`
+	// The HTTP Basic Auth Password to use when accessing the UDL.
+	HTTPBasicAuthPassword string `validate:"required"`
+	// The Base URL to use to access the UDL. The default is https://unifieddatalibrary.com.
+	BaseURL string `default:"https://unifieddatalibrary.com"`
+	// The Data Type that is read from the UDL. Acceptable values are AIS, ELSET, EPHEMERISSET, ORBITTRACK, POI, SIGACT, TRACK and WEATHERREPORT.
+	DataType string `validate:"inclusion=AIS|ELSET|EPHEMERISSET|ORBITTRACK|POI|SIGACT|TRACK|WEATHERREPORT" default:"AIS"`
+	// Whether the current rows are read as a snapshot before changes are read. Only applies to data types with a current endpoint (ELSET).
+	Snapshot bool `default:"true"`
+	// Comma-separated list of fields to read. When set, rows are queried through the tuple endpoint of the data type. The id and time fields are always included.
+	Columns string
+	// The time in RFC 3339 format from which records are read when the connector starts without a position. Defaults to the time the connector is first opened.
+	StartTime string
+	// The maximum number of records requested from the UDL in a single query.
+	BatchSize int `validate:"gt=0" default:"1000"`
+	// The maximum number of times a request is sent to the UDL when it fails with 429, a 5xx status or a network error. 4xx errors are never retried.
+	RetryMaxAttempts int `default:"5"`
+	// The maximum total time spent on a request to the UDL, including all attempts and the waits between them.
+	RetryTimeout time.Duration `default:"2m"`
+	// The maximum number of requests per second sent to the UDL. 0 disables the limit.
+	RateLimit float64 `default:"0"`
+	// The maximum number of requests sent to the UDL in a single burst when rateLimit is set.
+	RateLimitBurst int `default:"1"`
+	// How far behind the current time a window of records closes, so records whose creation only becomes visible in the UDL after a delay are still read.
+	WindowLag time.Duration `default:"1m"`
+	// How far before a window the event time of a record, e.g. ts or epoch, may be. The UDL requires a bound on the event time in every query; records created with an older event time are not read.
+	EventTimeLookback time.Duration `default:"720h"`
+}
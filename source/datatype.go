@@ -0,0 +1,217 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+type (
+	countFunc        func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error)
+	findFunc         func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error)
+	tupleFunc        func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error)
+	currentFunc      func(ctx context.Context, c udl.ClientInterface, reqEditors ...udl.RequestEditorFn) (*http.Response, error)
+	currentTupleFunc func(ctx context.Context, c udl.ClientInterface, columns string, reqEditors ...udl.RequestEditorFn) (*http.Response, error)
+	decodeFunc       func(body []byte) ([]row, error)
+)
+
+// dataType describes how a queryable UDL resource is read. The generated client
+// has a differently named operation and params struct for every resource,
+// the entries in dataTypes adapt them to a common signature.
+type dataType struct {
+	// requiredField is the event time field the UDL requires in every query
+	// of the resource. Windows are applied to windowField instead, rows
+	// ingested late with an older event time would otherwise land in a
+	// window that was already read. The required field is only bounded from
+	// below, eventTimeLookback before the window.
+	requiredField string
+	// idField is the field holding the unique identifier of a row.
+	idField string
+
+	count countFunc
+	find  findFunc
+	tuple tupleFunc
+	// current returns the snapshot rows, it is nil for resources that don't
+	// support snapshots.
+	current currentFunc
+	// currentTuple returns the configured columns of the snapshot rows, it
+	// is set whenever current is.
+	currentTuple currentTupleFunc
+
+	decode decodeFunc
+}
+
+// dataTypes maps the dataType config value to the UDL resource it reads.
+var dataTypes = map[string]dataType{
+	"AIS": {
+		requiredField: "ts",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountDuplicate(ctx, &udl.CountDuplicateParams{Ts: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll(ctx, &udl.FindAllParams{Ts: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples(ctx, &udl.FindAllTuplesParams{Columns: columns, Ts: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.AISAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"ELSET": {
+		requiredField: "epoch",
+		idField:       "idElset",
+		count: func(ctx context.Context, c udl.ClientInterface, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountREST24(ctx, &udl.CountREST24Params{}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllWithStream8(ctx, &udl.FindAllWithStream8Params{}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples29(ctx, &udl.FindAllTuples29Params{Columns: columns}, reqEditors...)
+		},
+		current: func(ctx context.Context, c udl.ClientInterface, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.Current1(ctx, reqEditors...)
+		},
+		currentTuple: func(ctx context.Context, c udl.ClientInterface, columns string, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CurrentTuple(ctx, &udl.CurrentTupleParams{Columns: columns}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.ElsetAbridged) (*string, *time.Time) { return v.IdElset, v.CreatedAt }),
+	},
+	"EPHEMERISSET": {
+		requiredField: "pointStartTime",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountREST27(ctx, &udl.CountREST27Params{}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllWithStream10(ctx, &udl.FindAllWithStream10Params{}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, _ time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples33(ctx, &udl.FindAllTuples33Params{Columns: columns}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.EphemerisSetAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"ORBITTRACK": {
+		requiredField: "ts",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountDuplicate15(ctx, &udl.CountDuplicate15Params{Ts: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll62(ctx, &udl.FindAll62Params{Ts: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples66(ctx, &udl.FindAllTuples66Params{Columns: columns, Ts: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.OrbitTrackAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"SIGACT": {
+		requiredField: "reportDate",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountDuplicate16(ctx, &udl.CountDuplicate16Params{ReportDate: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll85(ctx, &udl.FindAll85Params{ReportDate: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples88(ctx, &udl.FindAllTuples88Params{Columns: columns, ReportDate: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.SigActAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"WEATHERREPORT": {
+		requiredField: "obTime",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.Count2(ctx, &udl.Count2Params{ObTime: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll102(ctx, &udl.FindAll102Params{ObTime: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples105(ctx, &udl.FindAllTuples105Params{Columns: columns, ObTime: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.WeatherReportAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"TRACK": {
+		requiredField: "ts",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountDuplicate18(ctx, &udl.CountDuplicate18Params{Ts: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll99(ctx, &udl.FindAll99Params{Ts: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples102(ctx, &udl.FindAllTuples102Params{Columns: columns, Ts: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.TrackAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+	"POI": {
+		requiredField: "ts",
+		idField:       "id",
+		count: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.CountREST51(ctx, &udl.CountREST51Params{Ts: from}, reqEditors...)
+		},
+		find: func(ctx context.Context, c udl.ClientInterface, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAll65(ctx, &udl.FindAll65Params{Ts: from}, reqEditors...)
+		},
+		tuple: func(ctx context.Context, c udl.ClientInterface, columns string, from time.Time, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+			return c.FindAllTuples68(ctx, &udl.FindAllTuples68Params{Columns: columns, Ts: from}, reqEditors...)
+		},
+		decode: decodeRows(func(v udl.POIAbridged) (*string, *time.Time) { return v.Id, v.CreatedAt }),
+	},
+}
+
+// DataTypeValues returns the supported dataType config values in sorted order.
+func DataTypeValues() []string {
+	values := make([]string, 0, len(dataTypes))
+	for k := range dataTypes {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// decodeRows returns a decodeFunc that decodes every row of a JSON array into
+// T to extract its id and creation time. The payload of the row is kept as it
+// was returned by the UDL.
+func decodeRows[T any](fields func(T) (id *string, createdAt *time.Time)) decodeFunc {
+	return func(body []byte) ([]row, error) {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, err
+		}
+		rows := make([]row, len(raw))
+		for i, r := range raw {
+			var v T
+			if err := json.Unmarshal(r, &v); err != nil {
+				return nil, err
+			}
+			id, createdAt := fields(v)
+			rows[i] = row{createdAt: createdAt, payload: r}
+			if id != nil {
+				rows[i].key = *id
+			}
+		}
+		return rows, nil
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+const (
+	modeSnapshot = "snapshot"
+	modeCDC      = "cdc"
+)
+
+// Position describes the time window the source is reading from and how many
+// rows of that window were already returned. While a snapshot is taken, From
+// is the time the snapshot was started at and Offset the number of snapshot
+// rows already read.
+type Position struct {
+	// Mode is either snapshot or cdc. An empty mode is treated as cdc.
+	Mode string `json:"mode,omitempty"`
+	// From is the inclusive lower bound of the window.
+	From time.Time `json:"from"`
+	// To is the inclusive upper bound of the window. It is zero when the
+	// window has not been opened yet.
+	To time.Time `json:"to"`
+	// Offset is the number of rows of the window that were already read.
+	Offset int `json:"offset"`
+}
+
+func ParsePosition(p sdk.Position) (Position, error) {
+	var pos Position
+	if p == nil {
+		return pos, nil
+	}
+	if err := json.Unmarshal(p, &pos); err != nil {
+		return Position{}, fmt.Errorf("invalid position %q: %w", string(p), err)
+	}
+	return pos, nil
+}
+
+func (p Position) ToSDKPosition() sdk.Position {
+	b, err := json.Marshal(p)
+	if err != nil {
+		// a Position always marshals, this can only be a programming error
+		panic(err)
+	}
+	return b
+}
+
+// open returns the position with its window closed at the given time.
+func (p Position) open(to time.Time) Position {
+	p.To = to
+	p.Offset = 0
+	return p
+}
+
+// next returns the position of the window that follows the current one.
+func (p Position) next() Position {
+	return Position{Mode: p.Mode, From: p.To.Add(time.Microsecond)}
+}
+
+// cdc returns the position at which change data capture starts once the
+// snapshot is complete.
+func (p Position) cdc() Position {
+	return Position{Mode: modeCDC, From: p.From}
+}
+
+func (p Position) isSnapshot() bool {
+	return p.Mode == modeSnapshot
+}
+
+func (p Position) isOpen() bool {
+	return !p.To.IsZero()
+}
@@ -0,0 +1,59 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+)
+
+func TestPosition_RoundTrip(t *testing.T) {
+	is := is.New(t)
+
+	want := Position{
+		From:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Offset: 42,
+	}
+	got, err := ParsePosition(want.ToSDKPosition())
+	is.NoErr(err)
+	is.Equal(got, want)
+}
+
+func TestParsePosition_Nil(t *testing.T) {
+	is := is.New(t)
+
+	got, err := ParsePosition(nil)
+	is.NoErr(err)
+	is.Equal(got, Position{})
+}
+
+func TestParsePosition_Invalid(t *testing.T) {
+	is := is.New(t)
+
+	_, err := ParsePosition(sdk.Position("not json"))
+	is.True(err != nil)
+}
+
+func TestPosition_Next(t *testing.T) {
+	is := is.New(t)
+
+	to := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	p := Position{From: to.Add(-time.Hour), To: to, Offset: 10}.next()
+	is.Equal(p, Position{From: to.Add(time.Microsecond)})
+}
@@ -0,0 +1,176 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+// windowField is the query field the time windows are applied to, the time a
+// row was created in the UDL.
+const windowField = "createdAt"
+
+// udlQueryTimeLayout is the microsecond precision ISO 8601 format the UDL
+// expects in query parameters.
+const udlQueryTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// timeRange formats an inclusive UDL range query between from and to.
+func timeRange(from, to time.Time) string {
+	return from.UTC().Format(udlQueryTimeLayout) + ".." + to.UTC().Format(udlQueryTimeLayout)
+}
+
+// page returns the query parameters to fetch limit rows starting at offset.
+func page(offset, limit int) map[string]string {
+	return map[string]string{
+		"firstResult": strconv.Itoa(offset),
+		"maxResults":  strconv.Itoa(limit),
+	}
+}
+
+// count returns the number of rows inside the window.
+func (s *Source) count(ctx context.Context, pos Position) (int, error) {
+	resp, err := s.dataType.count(ctx, s.client, pos.From, s.window(pos))
+	if err != nil {
+		return 0, err
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return 0, err
+	}
+	return parseCount(body)
+}
+
+// find returns the next page of rows inside the window, ordered by the time
+// field so that offsets select the same rows on every request. If columns are
+// configured only those are queried through the tuple operation.
+func (s *Source) find(ctx context.Context, pos Position) ([]row, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	if s.Config.Columns != "" {
+		resp, err = s.dataType.tuple(ctx, s.client, s.columns(), pos.From, s.window(pos), s.page(pos))
+	} else {
+		resp, err = s.dataType.find(ctx, s.client, pos.From, s.window(pos), s.page(pos))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(resp)
+}
+
+// current returns the next page of the snapshot. Like find it is sorted and
+// only queries the configured columns, so snapshot and change records have
+// the same shape.
+func (s *Source) current(ctx context.Context, pos Position) ([]row, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	if s.Config.Columns != "" {
+		resp, err = s.dataType.currentTuple(ctx, s.client, s.columns(), s.page(pos))
+	} else {
+		resp, err = s.dataType.current(ctx, s.client, s.page(pos))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(resp)
+}
+
+func (s *Source) decode(resp *http.Response) ([]row, error) {
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return nil, errors.New("unexpected response content type: " + resp.Header.Get("Content-Type"))
+	}
+	return s.dataType.decode(body)
+}
+
+// window returns a request editor that restricts a query to the rows whose
+// creation time is inside the window. The required field of the data type
+// gets a lower bound reaching back eventTimeLookback, so rows with an event
+// time older than the window are still found by their creation time.
+func (s *Source) window(pos Position) udl.RequestEditorFn {
+	q := map[string]string{windowField: timeRange(pos.From, pos.To)}
+	if f := s.dataType.requiredField; f != "" {
+		q[f] = ">" + pos.From.Add(-s.Config.EventTimeLookback).UTC().Format(udlQueryTimeLayout)
+	}
+	return udl.WithQuery(q)
+}
+
+// page returns a request editor that selects the page of the window starting
+// at the offset of pos. Rows are sorted by their creation time and then by
+// id, so that offsets select the same rows on every request.
+func (s *Source) page(pos Position) udl.RequestEditorFn {
+	pageQuery := udl.WithQuery(page(pos.Offset, s.Config.BatchSize))
+	order := udl.WithSort(windowField, s.dataType.idField)
+	return func(ctx context.Context, req *http.Request) error {
+		if err := pageQuery(ctx, req); err != nil {
+			return err
+		}
+		return order(ctx, req)
+	}
+}
+
+// columns returns the configured columns, including the id and creation time
+// fields needed to build records and positions.
+func (s *Source) columns() string {
+	columns := strings.Split(s.Config.Columns, ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	for _, f := range []string{s.dataType.idField, windowField} {
+		if !slices.Contains(columns, f) {
+			columns = append(columns, f)
+		}
+	}
+	return strings.Join(columns, ",")
+}
+
+// readBody reads and closes the response body and returns an error including
+// the body if the UDL did not answer with a successful status code.
+func readBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unsuccessful status code returned %d; response: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// parseCount parses the plain text body returned by the UDL count endpoints.
+func parseCount(body []byte) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid count response %q: %w", string(body), err)
+	}
+	return n, nil
+}
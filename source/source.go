@@ -0,0 +1,228 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+const (
+	retryMinBackoff = 500 * time.Millisecond
+	retryMaxBackoff = 30 * time.Second
+)
+
+type Source struct {
+	sdk.UnimplementedSource
+	Config Config
+	client udl.ClientInterface
+
+	dataType dataType
+	position Position
+	buffer   []sdk.Record
+}
+
+func NewSource() sdk.Source {
+	return sdk.SourceWithMiddleware(&Source{}, sdk.DefaultSourceMiddleware()...)
+}
+
+func (s *Source) Parameters() map[string]sdk.Parameter {
+	return s.Config.Parameters()
+}
+
+func (s *Source) Configure(ctx context.Context, cfg map[string]string) error {
+	sdk.Logger(ctx).Debug().Msg("Configuring Source connector...")
+	err := sdk.Util.ParseConfig(cfg, &s.Config)
+	if err != nil {
+		sdk.Logger(ctx).Err(err).Msgf("invalid config")
+		return err
+	}
+	dt, ok := dataTypes[s.Config.DataType]
+	if !ok {
+		return fmt.Errorf("unsupported data type: %s", s.Config.DataType)
+	}
+	s.dataType = dt
+	if s.Config.StartTime != "" {
+		if _, err := time.Parse(time.RFC3339, s.Config.StartTime); err != nil {
+			return fmt.Errorf("invalid startTime %q: %w", s.Config.StartTime, err)
+		}
+	}
+	return nil
+}
+
+func (s *Source) Open(ctx context.Context, pos sdk.Position) error {
+	c, err := udl.NewClientWithBasicAuth(s.Config.BaseURL, s.Config.HTTPBasicAuthUsername, s.Config.HTTPBasicAuthPassword,
+		udl.WithRateLimit(s.Config.RateLimit, s.Config.RateLimitBurst),
+		udl.WithRetry(udl.RetryConfig{
+			MaxAttempts: s.Config.RetryMaxAttempts,
+			MaxDuration: s.Config.RetryTimeout,
+			MinBackoff:  retryMinBackoff,
+			MaxBackoff:  retryMaxBackoff,
+		}))
+	if err != nil {
+		return err
+	}
+	s.client = c
+
+	s.position, err = ParsePosition(pos)
+	if err != nil {
+		return err
+	}
+	if pos == nil {
+		s.position.From = time.Now().UTC()
+		if s.Config.StartTime != "" {
+			// already validated in Configure
+			s.position.From, _ = time.Parse(time.RFC3339, s.Config.StartTime)
+		}
+		if s.Config.Snapshot && s.dataType.current != nil {
+			s.position.Mode = modeSnapshot
+		}
+	}
+	sdk.Logger(ctx).Info().Msgf("reading %s from %s (mode %q, offset %d)", s.Config.DataType, s.position.From, s.position.Mode, s.position.Offset)
+	return nil
+}
+
+func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
+	if len(s.buffer) == 0 {
+		if err := s.fetch(ctx); err != nil {
+			return sdk.Record{}, err
+		}
+		if len(s.buffer) == 0 {
+			return sdk.Record{}, sdk.ErrBackoffRetry
+		}
+	}
+
+	rec := s.buffer[0]
+	s.buffer = s.buffer[1:]
+	return rec, nil
+}
+
+func (s *Source) Ack(ctx context.Context, pos sdk.Position) error {
+	sdk.Logger(ctx).Trace().Msgf("ack position %s", string(pos))
+	return nil
+}
+
+func (s *Source) Teardown(ctx context.Context) error {
+	// Teardown signals to the plugin that there will be no more calls to any
+	// other function. After Teardown returns, the plugin should be ready for a
+	// graceful shutdown.
+	return nil
+}
+
+// row is a single UDL row of any data type, reduced to what is needed to
+// build a record.
+type row struct {
+	key       string
+	createdAt *time.Time
+	payload   json.RawMessage
+}
+
+// fetch fills the buffer with the next page of rows.
+func (s *Source) fetch(ctx context.Context) error {
+	if s.position.isSnapshot() {
+		return s.fetchSnapshot(ctx)
+	}
+	return s.fetchWindow(ctx)
+}
+
+// fetchSnapshot fills the buffer with the next page of the snapshot and
+// switches to change data capture once the snapshot is exhausted.
+func (s *Source) fetchSnapshot(ctx context.Context) error {
+	rows, err := s.current(ctx, s.position)
+	if err != nil {
+		return fmt.Errorf("failed to query %s snapshot: %w", s.Config.DataType, err)
+	}
+
+	last := len(rows) < s.Config.BatchSize
+	for i, r := range rows {
+		pos := s.position
+		pos.Offset += i + 1
+		if last && i == len(rows)-1 {
+			pos = pos.cdc()
+		}
+		s.buffer = append(s.buffer, toRecord(r, pos, true))
+	}
+
+	if last {
+		sdk.Logger(ctx).Info().Msgf("snapshot complete, reading changes since %s", s.position.From)
+		s.position = s.position.cdc()
+	} else {
+		s.position.Offset += len(rows)
+	}
+	return nil
+}
+
+// fetchWindow fills the buffer with the next page of the current window,
+// opening a new window that ends windowLag before now if none is open.
+func (s *Source) fetchWindow(ctx context.Context) error {
+	if !s.position.isOpen() {
+		// the window closes windowLag behind now, rows whose createdAt is
+		// only visible after a delay would otherwise fall behind the window
+		to := time.Now().UTC().Add(-s.Config.WindowLag).Truncate(time.Microsecond)
+		if !to.After(s.position.From) {
+			return nil
+		}
+		s.position = s.position.open(to)
+
+		count, err := s.count(ctx, s.position)
+		if err != nil {
+			return fmt.Errorf("failed to count %s records: %w", s.Config.DataType, err)
+		}
+		sdk.Logger(ctx).Debug().Msgf("%d %s records between %s and %s", count, s.Config.DataType, s.position.From, s.position.To)
+		if count == 0 {
+			s.position = s.position.next()
+			return nil
+		}
+	}
+
+	rows, err := s.find(ctx, s.position)
+	if err != nil {
+		return fmt.Errorf("failed to query %s records: %w", s.Config.DataType, err)
+	}
+
+	last := len(rows) < s.Config.BatchSize
+	for i, r := range rows {
+		pos := s.position
+		pos.Offset += i + 1
+		if last && i == len(rows)-1 {
+			pos = pos.next()
+		}
+		s.buffer = append(s.buffer, toRecord(r, pos, false))
+	}
+
+	if last {
+		s.position = s.position.next()
+	} else {
+		s.position.Offset += len(rows)
+	}
+	return nil
+}
+
+func toRecord(r row, pos Position, snapshot bool) sdk.Record {
+	metadata := sdk.Metadata{}
+	if r.createdAt != nil {
+		metadata.SetCreatedAt(*r.createdAt)
+	}
+
+	if snapshot {
+		return sdk.Util.Source.NewRecordSnapshot(pos.ToSDKPosition(), metadata, sdk.RawData(r.key), sdk.RawData(r.payload))
+	}
+	return sdk.Util.Source.NewRecordCreate(pos.ToSDKPosition(), metadata, sdk.RawData(r.key), sdk.RawData(r.payload))
+}
@@ -0,0 +1,426 @@
+// Copyright © 2023 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/matryer/is"
+
+	"github.com/meroxa/conduit-connector-udl-public/udl"
+)
+
+type mockClient struct {
+	udl.ClientInterface
+	ais     []udl.AISAbridged
+	current []udl.ElsetAbridged
+	elsets  []udl.ElsetAbridged
+	// queries records the query string of every request sent
+	queries []string
+}
+
+func (c *mockClient) editQuery(ctx context.Context, path string, reqEditors []udl.RequestEditorFn) (*http.Request, error) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+	for _, e := range reqEditors {
+		if err := e(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	c.queries = append(c.queries, req.URL.RawQuery)
+	return req, nil
+}
+
+func (c *mockClient) CountDuplicate(ctx context.Context, params *udl.CountDuplicateParams, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if _, err := c.editQuery(ctx, "/udl/ais/count?ts="+exactTime(params.Ts), reqEditors); err != nil {
+		return nil, err
+	}
+	return countResponse(len(c.ais)), nil
+}
+
+func (c *mockClient) FindAll(ctx context.Context, params *udl.FindAllParams, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, err := c.editQuery(ctx, "/udl/ais?ts="+exactTime(params.Ts), reqEditors)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(c.ais, req), nil
+}
+
+func (c *mockClient) FindAllTuples(ctx context.Context, params *udl.FindAllTuplesParams, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, err := c.editQuery(ctx, "/udl/ais/tuple?columns="+url.QueryEscape(params.Columns)+"&ts="+exactTime(params.Ts), reqEditors)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(c.ais, req), nil
+}
+
+func (c *mockClient) Current1(ctx context.Context, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, err := c.editQuery(ctx, "/udl/elset/current", reqEditors)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(c.current, req), nil
+}
+
+func (c *mockClient) CurrentTuple(ctx context.Context, params *udl.CurrentTupleParams, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, err := c.editQuery(ctx, "/udl/elset/current/tuple?columns="+url.QueryEscape(params.Columns), reqEditors)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(c.current, req), nil
+}
+
+func (c *mockClient) CountREST24(ctx context.Context, params *udl.CountREST24Params, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	if _, err := c.editQuery(ctx, "/udl/elset/count", reqEditors); err != nil {
+		return nil, err
+	}
+	return countResponse(len(c.elsets)), nil
+}
+
+func (c *mockClient) FindAllWithStream8(ctx context.Context, params *udl.FindAllWithStream8Params, reqEditors ...udl.RequestEditorFn) (*http.Response, error) {
+	req, err := c.editQuery(ctx, "/udl/elset", reqEditors)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(c.elsets, req), nil
+}
+
+// exactTime formats t like the generated client sends a required time param.
+func exactTime(t time.Time) string {
+	return url.QueryEscape(t.Format(time.RFC3339Nano))
+}
+
+func countResponse(n int) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(strconv.Itoa(n))),
+	}
+}
+
+// pageResponse returns the page of rows selected by the paging parameters of
+// req as a JSON response.
+func pageResponse[T any](rows []T, req *http.Request) *http.Response {
+	offset, _ := strconv.Atoi(req.URL.Query().Get("firstResult"))
+	limit, _ := strconv.Atoi(req.URL.Query().Get("maxResults"))
+	end := offset + limit
+	if end > len(rows) {
+		end = len(rows)
+	}
+	if offset > end {
+		offset = end
+	}
+	body, _ := json.Marshal(rows[offset:end])
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBuffer(body)),
+	}
+}
+
+func sampleAis(n int) []udl.AISAbridged {
+	var rows []udl.AISAbridged
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("ais-%d", i)
+		rows = append(rows, udl.AISAbridged{
+			Id:                    &id,
+			ClassificationMarking: "U",
+			DataMode:              udl.AISAbridgedDataModeTEST,
+			Source:                "Spire",
+			Ts:                    time.Date(2023, 1, 1, 0, 0, i, 0, time.UTC),
+		})
+	}
+	return rows
+}
+
+func sampleElsets(prefix string, n int) []udl.ElsetAbridged {
+	var rows []udl.ElsetAbridged
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		rows = append(rows, udl.ElsetAbridged{
+			IdElset:               &id,
+			ClassificationMarking: "U",
+			Source:                "18th SPCS",
+			Epoch:                 time.Date(2023, 1, 1, 0, 0, i, 0, time.UTC),
+		})
+	}
+	return rows
+}
+
+func TestConfigure(t *testing.T) {
+	is := is.New(t)
+	src := Source{}
+	err := src.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"startTime":             "2023-01-01T00:00:00Z",
+	})
+	is.NoErr(err)
+	is.Equal(src.Config.StartTime, "2023-01-01T00:00:00Z")
+	is.True(src.dataType.find != nil)
+
+	err = src.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"startTime":             "yesterday",
+	})
+	is.True(err != nil) // startTime must be RFC 3339
+
+	err = src.Configure(context.Background(), map[string]string{
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIRCRAFT",
+	})
+	is.True(err != nil) // dataType must be registered
+}
+
+func TestOpen(t *testing.T) {
+	is := is.New(t)
+	src := Source{}
+	ctx := context.Background()
+	err := src.Configure(ctx, map[string]string{
+		"baseURL":               "https://example.com",
+		"httpBasicAuthUsername": "user",
+		"httpBasicAuthPassword": "pass",
+		"dataType":              "AIS",
+		"retryMaxAttempts":      "3",
+		"rateLimit":             "2.5",
+	})
+	is.NoErr(err)
+	is.Equal(src.Config.RetryMaxAttempts, 3)
+	is.Equal(src.Config.RateLimit, 2.5)
+
+	err = src.Open(ctx, nil)
+	is.NoErr(err)
+	is.True(src.client != nil)
+}
+
+func TestRead_Pages(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := &mockClient{ais: sampleAis(5)}
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := Source{
+		Config:   Config{DataType: "AIS", BatchSize: 2, WindowLag: time.Minute, EventTimeLookback: 24 * time.Hour},
+		client:   client,
+		dataType: dataTypes["AIS"],
+		position: Position{From: from},
+	}
+
+	var recs []sdk.Record
+	for i := 0; i < 5; i++ {
+		rec, err := src.Read(ctx)
+		is.NoErr(err)
+		recs = append(recs, rec)
+	}
+	client.ais = nil
+	_, err := src.Read(ctx)
+	is.Equal(err, sdk.ErrBackoffRetry) // window exhausted, nothing new yet
+
+	for i, rec := range recs {
+		is.Equal(rec.Operation, sdk.OperationCreate)
+		is.Equal(rec.Key, sdk.RawData(fmt.Sprintf("ais-%d", i)))
+
+		var row udl.AISAbridged
+		is.NoErr(json.Unmarshal(rec.Payload.After.Bytes(), &row))
+		is.Equal(*row.Id, fmt.Sprintf("ais-%d", i))
+	}
+
+	pos, err := ParsePosition(recs[2].Position)
+	is.NoErr(err)
+	is.Equal(pos.From, from)
+	is.Equal(pos.Offset, 3)
+
+	// the last record of a window points at the start of the next window
+	pos, err = ParsePosition(recs[4].Position)
+	is.NoErr(err)
+	is.True(pos.From.After(from))
+	is.True(!pos.isOpen())
+	is.True(pos.From.Before(time.Now().Add(-time.Minute))) // the window closes windowLag behind now
+
+	q, err := url.ParseQuery(client.queries[1])
+	is.NoErr(err)
+	is.True(strings.HasPrefix(q.Get("createdAt"), "2023-01-01T00:00:00.000000Z..")) // range query from the window start
+	is.Equal(q.Get("ts"), ">2022-12-31T00:00:00.000000Z")                           // the required ts is bounded by eventTimeLookback
+	is.Equal(q["sort"], []string{"createdAt,ASC", "id,ASC"})
+	is.Equal(q.Get("firstResult"), "0")
+	is.Equal(q.Get("maxResults"), "2")
+}
+
+func TestRead_WindowLag(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{ais: sampleAis(1)}
+	src := Source{
+		Config:   Config{DataType: "AIS", BatchSize: 2, WindowLag: time.Minute},
+		client:   client,
+		dataType: dataTypes["AIS"],
+		position: Position{From: time.Now().UTC().Add(-time.Second)},
+	}
+
+	_, err := src.Read(context.Background())
+	is.Equal(err, sdk.ErrBackoffRetry)
+	is.Equal(len(client.queries), 0) // no window is opened before windowLag passed
+	is.True(!src.position.isOpen())
+}
+
+func TestRead_ResumeOffset(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := &mockClient{ais: sampleAis(5)}
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	src := Source{
+		Config:   Config{DataType: "AIS", BatchSize: 10},
+		client:   client,
+		dataType: dataTypes["AIS"],
+		position: Position{From: from, To: to, Offset: 3},
+	}
+
+	rec, err := src.Read(ctx)
+	is.NoErr(err)
+	is.Equal(rec.Key, sdk.RawData("ais-3"))
+	is.Equal(len(client.queries), 1) // open window is not counted again
+}
+
+func TestRead_ElsetSnapshotThenCDC(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := &mockClient{
+		current: sampleElsets("current", 3),
+		elsets:  sampleElsets("new", 1),
+	}
+	started := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := Source{
+		Config:   Config{DataType: "ELSET", BatchSize: 2},
+		client:   client,
+		dataType: dataTypes["ELSET"],
+		position: Position{Mode: modeSnapshot, From: started},
+	}
+
+	var recs []sdk.Record
+	for i := 0; i < 4; i++ {
+		rec, err := src.Read(ctx)
+		is.NoErr(err)
+		recs = append(recs, rec)
+	}
+
+	for i := 0; i < 3; i++ {
+		is.Equal(recs[i].Operation, sdk.OperationSnapshot)
+		is.Equal(recs[i].Key, sdk.RawData(fmt.Sprintf("current-%d", i)))
+	}
+	is.Equal(recs[3].Operation, sdk.OperationCreate)
+	is.Equal(recs[3].Key, sdk.RawData("new-0"))
+
+	// a snapshot position in the middle of the snapshot resumes the snapshot
+	pos, err := ParsePosition(recs[0].Position)
+	is.NoErr(err)
+	is.Equal(pos, Position{Mode: modeSnapshot, From: started, Offset: 1})
+
+	// the last snapshot record hands over to CDC starting at the snapshot time
+	pos, err = ParsePosition(recs[2].Position)
+	is.NoErr(err)
+	is.Equal(pos, Position{Mode: modeCDC, From: started})
+
+	q, err := url.ParseQuery(client.queries[len(client.queries)-1])
+	is.NoErr(err)
+	is.True(strings.HasPrefix(q.Get("createdAt"), "2023-01-01T00:00:00.000000Z..")) // CDC filters on createdAt
+	is.True(q.Has("epoch"))                                                         // the UDL requires a bound on epoch
+}
+
+func TestRead_ElsetSnapshotColumns(t *testing.T) {
+	is := is.New(t)
+	client := &mockClient{current: sampleElsets("current", 3)}
+	src := Source{
+		Config:   Config{DataType: "ELSET", BatchSize: 2, Columns: "idOnOrbit"},
+		client:   client,
+		dataType: dataTypes["ELSET"],
+		position: Position{Mode: modeSnapshot, From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	rec, err := src.Read(context.Background())
+	is.NoErr(err)
+	is.Equal(rec.Operation, sdk.OperationSnapshot)
+
+	q, err := url.ParseQuery(client.queries[0])
+	is.NoErr(err)
+	is.Equal(q.Get("columns"), "idOnOrbit,idElset,createdAt") // the snapshot queries the same columns as CDC
+	is.Equal(q["sort"], []string{"createdAt,ASC", "idElset,ASC"})
+	is.Equal(q.Get("firstResult"), "0")
+}
+
+func TestRead_Columns(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := &mockClient{ais: sampleAis(1)}
+	src := Source{
+		Config:   Config{DataType: "AIS", BatchSize: 10, Columns: "mmsi, lat,lon"},
+		client:   client,
+		dataType: dataTypes["AIS"],
+		position: Position{From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	rec, err := src.Read(ctx)
+	is.NoErr(err)
+	is.Equal(rec.Key, sdk.RawData("ais-0"))
+
+	q, err := url.ParseQuery(client.queries[1])
+	is.NoErr(err)
+	is.Equal(q.Get("columns"), "mmsi,lat,lon,id,createdAt") // id and time fields are always queried
+}
+
+func TestWindow_AllDataTypes(t *testing.T) {
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	pos := Position{From: from, To: from.Add(time.Hour)}
+	for name, dt := range dataTypes {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			src := Source{Config: Config{EventTimeLookback: 24 * time.Hour}, dataType: dt}
+			req, err := http.NewRequest(http.MethodGet, "https://example.com/udl", nil)
+			is.NoErr(err)
+			is.NoErr(src.window(pos)(context.Background(), req))
+
+			q := req.URL.Query()
+			is.Equal(q.Get("createdAt"), "2023-01-02T00:00:00.000000Z..2023-01-02T01:00:00.000000Z") // every data type is windowed by createdAt
+			is.True(dt.requiredField != "")
+			is.Equal(q.Get(dt.requiredField), ">2023-01-01T00:00:00.000000Z")
+		})
+	}
+}
+
+func TestDataTypeValues(t *testing.T) {
+	is := is.New(t)
+
+	// the dataType validation has to list exactly the registered data types
+	var inclusion []string
+	for _, v := range (Config{}).Parameters()["dataType"].Validations {
+		if in, ok := v.(sdk.ValidationInclusion); ok {
+			inclusion = in.List
+		}
+	}
+	sort.Strings(inclusion)
+	is.Equal(inclusion, DataTypeValues())
+}
@@ -2,12 +2,12 @@ package udl
 
 import "github.com/deepmap/oapi-codegen/pkg/securityprovider"
 
-func NewClientWithBasicAuth(url, username, password string) (*Client, error) {
+func NewClientWithBasicAuth(url, username, password string, opts ...ClientOption) (*Client, error) {
 	authProvider, err := generateBasicAuth(username, password)
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(url, WithRequestEditorFn(authProvider.Intercept))
+	return NewClient(url, append([]ClientOption{WithRequestEditorFn(authProvider.Intercept)}, opts...)...)
 }
 
 func generateBasicAuth(username, password string) (*securityprovider.SecurityProviderBasicAuth, error) {
@@ -0,0 +1,35 @@
+package udl
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithQuery returns a request editor that sets query parameters on a
+// generated client request, overriding the ones of the params struct. The
+// generated params only allow exact matches, the editor is used to send range
+// queries, paging parameters and fields the params struct does not have.
+func WithQuery(values map[string]string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		q := req.URL.Query()
+		for k, v := range values {
+			q.Set(k, v)
+		}
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+}
+
+// WithSort returns a request editor that sorts the rows of a query ascending
+// by the fields, in order of precedence.
+func WithSort(fields ...string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		q := req.URL.Query()
+		q.Del("sort")
+		for _, f := range fields {
+			q.Add("sort", f+",ASC")
+		}
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+}
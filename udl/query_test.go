@@ -0,0 +1,23 @@
+package udl
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestQueryEditors(t *testing.T) {
+	is := is.New(t)
+	req, err := http.NewRequest(http.MethodGet, "https://udl.test/udl/ais?ts=2023-01-01T00:00:00Z&columns=id&sort=ts,DESC", nil)
+	is.NoErr(err)
+
+	is.NoErr(WithQuery(map[string]string{"ts": ">a", "columns": "id,ts"})(context.Background(), req))
+	is.NoErr(WithSort("createdAt", "id")(context.Background(), req))
+
+	q := req.URL.Query()
+	is.Equal(q.Get("ts"), ">a")
+	is.Equal(q.Get("columns"), "id,ts")                      // set values override existing ones
+	is.Equal(q["sort"], []string{"createdAt,ASC", "id,ASC"}) // in order of precedence
+}
@@ -0,0 +1,42 @@
+package udl
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit wraps the Doer of the client in a token bucket limiter, so
+// requests to any endpoint are sent at most rps times per second, with bursts
+// of up to burst requests. A non-positive rps disables the limiter.
+//
+// It has to be passed before WithRetry, so retried attempts are limited too,
+// and after WithHTTPClient, if that option is used.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) error {
+		if rps <= 0 {
+			return nil
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		doer := c.Client
+		if doer == nil {
+			doer = &http.Client{}
+		}
+		c.Client = &rateLimitDoer{doer: doer, limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		return nil
+	}
+}
+
+type rateLimitDoer struct {
+	doer    HttpRequestDoer
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitDoer) Do(req *http.Request) (*http.Response, error) {
+	if err := r.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return r.doer.Do(req)
+}
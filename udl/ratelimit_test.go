@@ -0,0 +1,50 @@
+package udl
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestRateLimit_SharedAcrossRequests(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{200, 200, 200, 200}}
+	c, err := NewClient("https://example.com", WithHTTPClient(doer), WithRateLimit(50, 1))
+	is.NoErr(err)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		req := newRequest(t)
+		_, err := c.Client.Do(req)
+		is.NoErr(err)
+	}
+	is.True(time.Since(start) >= 50*time.Millisecond) // 3 requests had to wait 20ms each
+	is.Equal(len(doer.bodies), 4)
+}
+
+func TestRateLimit_CanceledContext(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{200, 200}}
+	c, err := NewClient("https://example.com", WithHTTPClient(doer), WithRateLimit(0.001, 1))
+	is.NoErr(err)
+
+	_, err = c.Client.Do(newRequest(t))
+	is.NoErr(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Client.Do(newRequest(t).WithContext(ctx))
+	is.True(err != nil)           // waiting for a token would exceed the deadline
+	is.Equal(len(doer.bodies), 1) // the second request was never sent
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	is := is.New(t)
+	c, err := NewClient("https://example.com", WithRateLimit(0, 1))
+	is.NoErr(err)
+	_, ok := c.Client.(*http.Client)
+	is.True(ok)
+}
@@ -0,0 +1,207 @@
+package udl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryConfig configures how requests failing with a transient error are
+// retried.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of times a request is sent, including
+	// the first attempt. Values below 2 disable retries.
+	MaxAttempts int
+	// MaxDuration limits the total time spent on a request including all
+	// attempts and the waits between them. Zero means no limit.
+	MaxDuration time.Duration
+	// MinBackoff is the upper bound of the wait before the first retry, it is
+	// doubled with every further attempt.
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration
+}
+
+// WithRetry wraps the Doer of the client so requests failing with a transient
+// network error, such as a timeout or a reset connection, 429 Too Many Requests or a 5xx status are retried with
+// exponential backoff and full jitter. A Retry-After header sent by the UDL
+// takes precedence over the computed backoff. Other responses, including 4xx
+// validation errors, are returned right away.
+//
+// It has to be passed after WithHTTPClient, if that option is used.
+func WithRetry(cfg RetryConfig) ClientOption {
+	return func(c *Client) error {
+		doer := c.Client
+		if doer == nil {
+			doer = &http.Client{}
+		}
+		c.Client = &retryDoer{doer: doer, cfg: cfg, sleep: sleepContext}
+		return nil
+	}
+}
+
+type retryDoer struct {
+	doer HttpRequestDoer
+	cfg  RetryConfig
+	// sleep waits for d or until the request context is done.
+	sleep func(req *http.Request, d time.Duration) error
+}
+
+func (r *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	if r.cfg.MaxDuration <= 0 {
+		return r.do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), r.cfg.MaxDuration)
+	resp, err := r.do(req.WithContext(ctx))
+	if err != nil || resp.Body == nil {
+		cancel()
+		return resp, err
+	}
+	// the caller reads the body after Do returns, so the deadline is only
+	// released when the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// do sends the request until it succeeds, fails with a permanent error or
+// the attempts are used up.
+func (r *retryDoer) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		resp, err := r.doer.Do(req)
+		if attempt >= r.cfg.MaxAttempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := r.backoff(attempt, resp)
+		if r.cfg.MaxDuration > 0 && time.Since(start)+wait > r.cfg.MaxDuration {
+			return resp, err
+		}
+		if req.Body != nil {
+			if req.GetBody == nil {
+				// the body was consumed and can't be sent again
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if err := r.sleep(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns how long to wait before the next attempt.
+func (r *retryDoer) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	ceiling := r.cfg.MinBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > r.cfg.MaxBackoff {
+		ceiling = r.cfg.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// errors caused by the caller giving up or the deadline passing are
+		// not transient
+		return req.Context().Err() == nil && transientError(err)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// transientError reports whether a transport error is worth retrying: a
+// timeout, a temporary DNS failure, or a connection that could not be made or
+// was lost. Certificate and TLS errors, unknown hosts and invalid URLs fail
+// the same way on every attempt.
+func transientError(err error) bool {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalidCert      x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		dnsErr           *net.DNSError
+		opErr            *net.OpError
+		netErr           net.Error
+	)
+	switch {
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname),
+		errors.As(err, &invalidCert), errors.As(err, &recordHeader):
+		return false
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	case errors.As(err, &opErr):
+		// remote TLS alerts are reported as op errors as well
+		return opErr.Op == "dial" || opErr.Op == "read" || opErr.Op == "write"
+	}
+	return false
+}
+
+// cancelBody releases the context of a request when its response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleepContext(req *http.Request, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,224 @@
+package udl
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// scriptedDoer returns the scripted responses and errors in order and records
+// the bodies of the requests it received.
+type scriptedDoer struct {
+	codes  []int
+	errs   []error
+	header http.Header
+	bodies []string
+}
+
+func (d *scriptedDoer) Do(req *http.Request) (*http.Response, error) {
+	b, _ := io.ReadAll(req.Body)
+	d.bodies = append(d.bodies, string(b))
+	i := len(d.bodies) - 1
+	if d.errs != nil && d.errs[i] != nil {
+		return nil, d.errs[i]
+	}
+	return &http.Response{
+		StatusCode: d.codes[i],
+		Header:     d.header,
+		Body:       io.NopCloser(strings.NewReader("validation failed")),
+	}, nil
+}
+
+func newRetryDoer(doer HttpRequestDoer, cfg RetryConfig) (*retryDoer, *[]time.Duration) {
+	var waits []time.Duration
+	return &retryDoer{
+		doer: doer,
+		cfg:  cfg,
+		sleep: func(req *http.Request, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		},
+	}, &waits
+}
+
+func newRequest(t *testing.T) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/filedrop/udl-ais", bytes.NewReader([]byte(`[{"mmsi":1}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRetry_RetriesTransientStatus(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK}}
+	r, waits := newRetryDoer(doer, RetryConfig{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusOK)
+	is.Equal(len(*waits), 2)
+	is.True((*waits)[0] <= time.Second)
+	is.True((*waits)[1] <= 2*time.Second)
+	is.Equal(doer.bodies, []string{`[{"mmsi":1}]`, `[{"mmsi":1}]`, `[{"mmsi":1}]`}) // the body is resent
+}
+
+func TestRetry_FailsFastOnClientError(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{http.StatusBadRequest, http.StatusOK}}
+	r, waits := newRetryDoer(doer, RetryConfig{MaxAttempts: 5})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusBadRequest)
+	body, _ := io.ReadAll(resp.Body)
+	is.Equal(string(body), "validation failed") // the body is left for the caller
+	is.Equal(len(*waits), 0)
+}
+
+func TestRetry_MaxAttempts(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{500, 500, 500, 200}}
+	r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, 500)
+	is.Equal(len(doer.bodies), 3)
+}
+
+func TestRetry_NetworkError(t *testing.T) {
+	is := is.New(t)
+	reset := &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}
+	doer := &scriptedDoer{codes: []int{0, 200}, errs: []error{reset, nil}}
+	r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, 200)
+}
+
+func TestRetry_PermanentTransportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "unknown authority", err: &url.Error{Op: "Post", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}},
+		{name: "hostname mismatch", err: &url.Error{Op: "Post", URL: "https://example.com", Err: x509.HostnameError{Host: "example.com"}}},
+		{name: "unsupported scheme", err: &url.Error{Op: "Post", URL: "htps://example.com", Err: errors.New("unsupported protocol scheme")}},
+		{name: "unknown host", err: &url.Error{Op: "Post", URL: "https://example.invalid", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}}},
+		{name: "plain error", err: errors.New("something went wrong")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			doer := &scriptedDoer{codes: []int{0, 200}, errs: []error{tt.err, nil}}
+			r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3})
+
+			_, err := r.Do(newRequest(t))
+			is.Equal(err, tt.err)
+			is.Equal(len(doer.bodies), 1) // not retried
+		})
+	}
+}
+
+func TestRetry_CanceledContext(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doer := &scriptedDoer{codes: []int{0, 200}, errs: []error{context.Canceled, nil}}
+	r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3})
+
+	_, err := r.Do(newRequest(t).WithContext(ctx))
+	is.True(errors.Is(err, context.Canceled))
+	is.Equal(len(doer.bodies), 1)
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{
+		codes:  []int{http.StatusTooManyRequests, http.StatusOK},
+		header: http.Header{"Retry-After": []string{"7"}},
+	}
+	r, waits := newRetryDoer(doer, RetryConfig{MaxAttempts: 3, MaxBackoff: time.Second})
+
+	_, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(*waits, []time.Duration{7 * time.Second}) // Retry-After wins over the backoff
+}
+
+func TestRetry_MaxDuration(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{
+		codes:  []int{http.StatusTooManyRequests, http.StatusOK},
+		header: http.Header{"Retry-After": []string{"60"}},
+	}
+	r, waits := newRetryDoer(doer, RetryConfig{MaxAttempts: 3, MaxDuration: time.Minute / 2})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	is.Equal(resp.StatusCode, http.StatusTooManyRequests) // waiting would exceed the deadline
+	is.Equal(len(*waits), 0)
+}
+
+// blockingDoer blocks every request until its context is done.
+type blockingDoer struct {
+	attempts int
+}
+
+func (d *blockingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.attempts++
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestRetry_MaxDurationCoversAttempts(t *testing.T) {
+	is := is.New(t)
+	doer := &blockingDoer{}
+	r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3, MaxDuration: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := r.Do(newRequest(t))
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.True(time.Since(start) < 5*time.Second) // the attempt in flight is cut off
+	is.Equal(doer.attempts, 1)
+}
+
+func TestRetry_MaxDurationBodyReadable(t *testing.T) {
+	is := is.New(t)
+	doer := &scriptedDoer{codes: []int{http.StatusBadRequest}}
+	r, _ := newRetryDoer(doer, RetryConfig{MaxAttempts: 3, MaxDuration: time.Minute})
+
+	resp, err := r.Do(newRequest(t))
+	is.NoErr(err)
+	body, err := io.ReadAll(resp.Body)
+	is.NoErr(err)
+	is.Equal(string(body), "validation failed") // the deadline is released on close
+	is.NoErr(resp.Body.Close())
+}
+
+func TestRetryAfter(t *testing.T) {
+	is := is.New(t)
+
+	d, ok := retryAfter("120")
+	is.True(ok)
+	is.Equal(d, 2*time.Minute)
+
+	d, ok = retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	is.True(ok)
+	is.True(d > 59*time.Minute)
+
+	_, ok = retryAfter("soon")
+	is.True(!ok)
+}